@@ -0,0 +1,70 @@
+package gcache
+
+import (
+	"context"
+	"time"
+)
+
+// Entry 批量写入的键值对
+type Entry struct {
+	Key string
+	Val any
+}
+
+// Result 批量操作中单个 key 的结果, 顺序和传入的 key 一致
+type Result struct {
+	Key string
+	Val any
+	Err error
+}
+
+// BatchCache 支持批量操作的缓存
+// 单个 key 的失败记录在对应的 Result.Err 中, 返回的 error 表示整个操作都失败了
+type BatchCache interface {
+	Cache
+	GetMulti(ctx context.Context, keys []string) ([]Result, error)
+	SetMulti(ctx context.Context, entries []Entry, expiration time.Duration) ([]Result, error)
+	DeleteMulti(ctx context.Context, keys []string) ([]Result, error)
+}
+
+// NewBatchCache c 本身支持批量操作时直接返回 c, 否则逐个 key 调用 c 的方法
+func NewBatchCache(c Cache) BatchCache {
+	if bc, ok := c.(BatchCache); ok {
+		return bc
+	}
+	return &loopBatchCache{Cache: c}
+}
+
+// loopBatchCache 没有原生批量操作的缓存的兜底实现
+type loopBatchCache struct {
+	Cache
+}
+
+func (l *loopBatchCache) unwrap() Cache {
+	return l.Cache
+}
+
+func (l *loopBatchCache) GetMulti(ctx context.Context, keys []string) ([]Result, error) {
+	res := make([]Result, len(keys))
+	for i, key := range keys {
+		val, err := l.Get(ctx, key)
+		res[i] = Result{Key: key, Val: val, Err: err}
+	}
+	return res, nil
+}
+
+func (l *loopBatchCache) SetMulti(ctx context.Context, entries []Entry, expiration time.Duration) ([]Result, error) {
+	res := make([]Result, len(entries))
+	for i, e := range entries {
+		res[i] = Result{Key: e.Key, Err: l.Set(ctx, e.Key, e.Val, expiration)}
+	}
+	return res, nil
+}
+
+func (l *loopBatchCache) DeleteMulti(ctx context.Context, keys []string) ([]Result, error) {
+	res := make([]Result, len(keys))
+	for i, key := range keys {
+		res[i] = Result{Key: key, Err: l.Delete(ctx, key)}
+	}
+	return res, nil
+}
@@ -0,0 +1,247 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/NotFound1911/gcache/clock"
+	"sync"
+	"time"
+)
+
+// BulkLoadFunc 一次加载多个 key, 返回结果中不存在的 key 视为数据不存在
+type BulkLoadFunc func(ctx context.Context, keys []string) (map[string]any, error)
+
+type BatchReadThroughOption func(r *BatchReadThroughCache)
+
+// BatchReadThroughCache 批量读穿透
+// 未命中的 key 会先攒一个很短的窗口期, 窗口期内所有调用方未命中的 key 合并成一次 BulkLoadFunc 调用
+type BatchReadThroughCache struct {
+	BatchCache
+	loadFunc   BulkLoadFunc
+	expiration time.Duration
+	window     time.Duration
+	maxBatch   int
+	timeout    time.Duration
+	strategy   ExpirationStrategy
+	clock      clock.Clock
+	stats      statsCounter
+
+	mu    sync.Mutex
+	batch *loadBatch // 正在攒的批次
+}
+
+// loadBatch 一批等待加载的 key
+type loadBatch struct {
+	ctx   context.Context
+	calls map[string]*loadCall
+	keys  []string
+	full  chan struct{} // 达到 maxBatch 之后关闭, 提前开始加载
+}
+
+type loadCall struct {
+	done chan struct{}
+	val  any
+	err  error
+}
+
+func NewBatchReadThroughCache(c Cache, loadFunc BulkLoadFunc, expiration time.Duration,
+	opts ...BatchReadThroughOption) *BatchReadThroughCache {
+	res := &BatchReadThroughCache{
+		BatchCache: NewBatchCache(c),
+		loadFunc:   loadFunc,
+		expiration: expiration,
+		window:     time.Millisecond * 2,
+		maxBatch:   100,
+		timeout:    time.Second * 10,
+		clock:      clock.New(),
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// BuildBatchReadThroughWithWindow 攒批的窗口期, 窗口期越长合并的越多, 但是未命中的延迟也越高
+func BuildBatchReadThroughWithWindow(window time.Duration) BatchReadThroughOption {
+	return func(r *BatchReadThroughCache) {
+		r.window = window
+	}
+}
+
+// BuildBatchReadThroughWithMaxBatch 一批最多加载多少个 key, 攒够了不等窗口期结束直接加载
+func BuildBatchReadThroughWithMaxBatch(maxBatch int) BatchReadThroughOption {
+	return func(r *BatchReadThroughCache) {
+		r.maxBatch = maxBatch
+	}
+}
+
+// BuildBatchReadThroughWithTimeout 一个批次加载和写回缓存的超时时间, 默认 10 秒
+func BuildBatchReadThroughWithTimeout(timeout time.Duration) BatchReadThroughOption {
+	return func(r *BatchReadThroughCache) {
+		r.timeout = timeout
+	}
+}
+
+// BuildBatchReadThroughWithExpirationStrategy 写回缓存的过期时间经过 strategy 调整
+// 整个批次使用批次中第一个 key 调整一次, 合并成一次 SetMulti, 不同批次之间的过期时间依旧是打散的
+func BuildBatchReadThroughWithExpirationStrategy(s ExpirationStrategy) BatchReadThroughOption {
+	return func(r *BatchReadThroughCache) {
+		r.strategy = s
+	}
+}
+
+func BuildBatchReadThroughWithClock(c clock.Clock) BatchReadThroughOption {
+	return func(r *BatchReadThroughCache) {
+		r.clock = c
+	}
+}
+
+func (r *BatchReadThroughCache) Get(ctx context.Context, key string) (any, error) {
+	val, err := r.BatchCache.Get(ctx, key)
+	if !errors.Is(err, ErrKeyNotFound) {
+		return val, err
+	}
+	call := r.enqueue(ctx, []string{key})[0]
+	select {
+	case <-call.done:
+		return call.val, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (r *BatchReadThroughCache) GetMulti(ctx context.Context, keys []string) ([]Result, error) {
+	res, err := r.BatchCache.GetMulti(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	var missed []string
+	var idx []int
+	for i := range res {
+		if errors.Is(res[i].Err, ErrKeyNotFound) {
+			missed = append(missed, res[i].Key)
+			idx = append(idx, i)
+		}
+	}
+	if len(missed) == 0 {
+		return res, nil
+	}
+	calls := r.enqueue(ctx, missed)
+	for j, call := range calls {
+		select {
+		case <-call.done:
+			res[idx[j]].Val, res[idx[j]].Err = call.val, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return res, nil
+}
+
+// enqueue 把 key 加入正在攒的批次, 同一批次中重复的 key 共用一次加载
+func (r *BatchReadThroughCache) enqueue(ctx context.Context, keys []string) []*loadCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := make([]*loadCall, len(keys))
+	for i, key := range keys {
+		if r.batch == nil {
+			r.batch = r.newBatch(ctx)
+		}
+		call, ok := r.batch.calls[key]
+		if !ok {
+			call = &loadCall{done: make(chan struct{})}
+			r.batch.calls[key] = call
+			r.batch.keys = append(r.batch.keys, key)
+		}
+		res[i] = call
+		if len(r.batch.keys) >= r.maxBatch {
+			close(r.batch.full)
+			r.batch = nil
+		}
+	}
+	return res
+}
+
+// newBatch 创建新的批次, 窗口期结束或者攒满之后开始加载
+// 批次是多个调用方共享的, 只保留创建批次的调用方 context 中的值, 不受它的取消影响
+func (r *BatchReadThroughCache) newBatch(ctx context.Context) *loadBatch {
+	b := &loadBatch{
+		ctx:   detachedContext{Context: ctx},
+		calls: make(map[string]*loadCall),
+		full:  make(chan struct{}),
+	}
+	timer := r.clock.NewTimer(r.window)
+	go func() {
+		select {
+		case <-timer.C():
+			r.mu.Lock()
+			if r.batch == b {
+				r.batch = nil
+			}
+			r.mu.Unlock()
+		case <-b.full:
+			timer.Stop()
+		}
+		r.load(b)
+	}()
+	return b
+}
+
+// load 加载一个批次, 成功加载的数据写回缓存
+func (r *BatchReadThroughCache) load(b *loadBatch) {
+	ctx, cancel := context.WithTimeout(b.ctx, r.timeout)
+	defer cancel()
+	vals, err := r.loadFunc(ctx, b.keys)
+	r.stats.loaded(err)
+	if err == nil {
+		err = r.setMulti(ctx, b.keys, vals)
+	}
+	for _, key := range b.keys {
+		call := b.calls[key]
+		val, ok := vals[key]
+		switch {
+		case ok:
+			// 写回缓存失败的时候, 数据依然是可用的
+			call.val, call.err = val, err
+		case err != nil:
+			call.err = err
+		default:
+			call.err = fmt.Errorf("%w, key: %s", ErrKeyNotFound, key)
+		}
+		close(call.done)
+	}
+}
+
+// setMulti 把加载到的数据写回缓存, 一个批次只有一次 SetMulti
+func (r *BatchReadThroughCache) setMulti(ctx context.Context, keys []string, vals map[string]any) error {
+	entries := make([]Entry, 0, len(vals))
+	for _, key := range keys {
+		if val, ok := vals[key]; ok {
+			entries = append(entries, Entry{Key: key, Val: val})
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	var err error
+	if r.strategy == nil {
+		_, err = r.BatchCache.SetMulti(ctx, entries, r.expiration)
+	} else {
+		expiration := r.strategy.Expiration(entries[0].Key, r.expiration)
+		_, err = setMultiAdjusted(ctx, r.BatchCache, entries, expiration)
+	}
+	if err != nil {
+		return fmt.Errorf("%w, 原因: %s", ErrFailedToRefreshCache, err.Error())
+	}
+	return nil
+}
+
+// Stats 底层缓存的统计数据, 加上加载的次数
+func (r *BatchReadThroughCache) Stats() Stats {
+	return loaderStats(r.BatchCache, &r.stats)
+}
+
+func (r *BatchReadThroughCache) ResetStats() {
+	resetLoaderStats(r.BatchCache, &r.stats)
+}
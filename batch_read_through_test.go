@@ -0,0 +1,154 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/NotFound1911/gcache/clock/clocktest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+type bulkLoader struct {
+	mu    sync.Mutex
+	calls [][]string
+	err   error
+}
+
+func (b *bulkLoader) load(ctx context.Context, keys []string) (map[string]any, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	b.calls = append(b.calls, sorted)
+	if b.err != nil {
+		return nil, b.err
+	}
+	res := make(map[string]any, len(keys))
+	for _, key := range keys {
+		if key != "invalid" {
+			res[key] = "val_" + key
+		}
+	}
+	return res, nil
+}
+
+func pendingKeys(r *BatchReadThroughCache) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.batch == nil {
+		return 0
+	}
+	return len(r.batch.keys)
+}
+
+func TestBatchReadThroughCache_Get(t *testing.T) {
+	local := NewMapCache(time.Minute)
+	defer local.Close()
+	require.NoError(t, local.Set(context.Background(), "cached", "val_cached", time.Minute))
+	clk := clocktest.NewFakeClock(time.Now())
+	loader := &bulkLoader{}
+	c := NewBatchReadThroughCache(local, loader.load, time.Minute,
+		BuildBatchReadThroughWithClock(clk), BuildBatchReadThroughWithWindow(time.Millisecond))
+
+	keys := []string{"key1", "key2", "key1", "invalid", "cached"}
+	results := make([]Result, len(keys))
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i int, key string) {
+			defer wg.Done()
+			val, err := c.Get(context.Background(), key)
+			results[i] = Result{Key: key, Val: val, Err: err}
+		}(i, key)
+	}
+	require.Eventually(t, func() bool {
+		return pendingKeys(c) == 3
+	}, time.Second, time.Millisecond)
+	clk.Advance(time.Millisecond)
+	wg.Wait()
+
+	assert.Equal(t, [][]string{{"invalid", "key1", "key2"}}, loader.calls)
+	assert.Equal(t, []Result{
+		{Key: "key1", Val: "val_key1"},
+		{Key: "key2", Val: "val_key2"},
+		{Key: "key1", Val: "val_key1"},
+		{Key: "invalid", Err: fmt.Errorf("%w, key: %s", ErrKeyNotFound, "invalid")},
+		{Key: "cached", Val: "val_cached"},
+	}, results)
+	val, err := local.Get(context.Background(), "key2")
+	require.NoError(t, err)
+	assert.Equal(t, "val_key2", val)
+	assert.Equal(t, int64(1), c.Stats().LoadSuccesses)
+}
+
+func TestBatchReadThroughCache_GetMulti(t *testing.T) {
+	local := NewMapCache(time.Minute)
+	defer local.Close()
+	require.NoError(t, local.Set(context.Background(), "cached", "val_cached", time.Minute))
+	clk := clocktest.NewFakeClock(time.Now())
+	loader := &bulkLoader{}
+	c := NewBatchReadThroughCache(local, loader.load, time.Minute,
+		BuildBatchReadThroughWithClock(clk), BuildBatchReadThroughWithMaxBatch(2))
+
+	done := make(chan struct{})
+	var res []Result
+	var err error
+	go func() {
+		defer close(done)
+		res, err = c.GetMulti(context.Background(), []string{"key1", "cached", "key2", "key3"})
+	}()
+	// 攒满两个的批次直接加载, 剩下的一个等待窗口期结束
+	require.Eventually(t, func() bool {
+		return pendingKeys(c) == 1
+	}, time.Second, time.Millisecond)
+	clk.Advance(time.Second)
+	<-done
+	require.NoError(t, err)
+	assert.Equal(t, []Result{
+		{Key: "key1", Val: "val_key1"},
+		{Key: "cached", Val: "val_cached"},
+		{Key: "key2", Val: "val_key2"},
+		{Key: "key3", Val: "val_key3"},
+	}, res)
+	assert.Len(t, loader.calls, 2)
+}
+
+func TestBatchReadThroughCache_LoadError(t *testing.T) {
+	local := NewMapCache(time.Minute)
+	defer local.Close()
+	loadErr := errors.New("mock db error")
+	loader := &bulkLoader{err: loadErr}
+	c := NewBatchReadThroughCache(local, loader.load, time.Minute)
+	_, err := c.Get(context.Background(), "key1")
+	assert.Equal(t, loadErr, err)
+	assert.Equal(t, int64(1), c.Stats().LoadFailures)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.Get(ctx, "key1")
+	assert.Equal(t, context.Canceled, err)
+}
+
+type traceIDKey struct{}
+
+func TestBatchReadThroughCache_Timeout(t *testing.T) {
+	local := NewMapCache(time.Minute)
+	defer local.Close()
+	var traceID any
+	c := NewBatchReadThroughCache(local, func(ctx context.Context, keys []string) (map[string]any, error) {
+		traceID = ctx.Value(traceIDKey{})
+		// 卡住的加载在超时之后结束
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, time.Minute, BuildBatchReadThroughWithTimeout(time.Millisecond*10))
+	ctx := context.WithValue(context.Background(), traceIDKey{}, "trace-1")
+	_, err := c.Get(ctx, "key1")
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, "trace-1", traceID)
+	assert.Equal(t, int64(1), c.Stats().LoadFailures)
+}
@@ -0,0 +1,126 @@
+package gcache
+
+import (
+	"context"
+	"fmt"
+	"github.com/NotFound1911/gcache/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestMapCache_Multi(t *testing.T) {
+	c := NewMapCache(time.Minute)
+	defer c.Close()
+	ctx := context.Background()
+	res, err := c.SetMulti(ctx, []Entry{{Key: "key1", Val: 1}, {Key: "key2", Val: 2}}, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, []Result{{Key: "key1"}, {Key: "key2"}}, res)
+
+	res, err = c.GetMulti(ctx, []string{"key1", "key3", "key2"})
+	require.NoError(t, err)
+	assert.Equal(t, []Result{
+		{Key: "key1", Val: 1},
+		{Key: "key3", Err: fmt.Errorf("%w, key: %s", ErrKeyNotFound, "key3")},
+		{Key: "key2", Val: 2},
+	}, res)
+
+	res, err = c.DeleteMulti(ctx, []string{"key1", "key3"})
+	require.NoError(t, err)
+	assert.Equal(t, []Result{{Key: "key1"}, {Key: "key3"}}, res)
+	_, err = c.Get(ctx, "key1")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	st := c.Stats()
+	assert.Equal(t, int64(2), st.Hits)
+	assert.Equal(t, int64(2), st.Misses)
+	assert.Equal(t, int64(2), st.Sets)
+}
+
+func TestMaxCntCache_SetMulti(t *testing.T) {
+	c := NewMaxCntCache(NewMapCache(time.Minute), 2)
+	defer c.Close()
+	res, err := c.SetMulti(context.Background(), []Entry{
+		{Key: "key1", Val: 1}, {Key: "key2", Val: 2}, {Key: "key3", Val: 3}, {Key: "key1", Val: 11},
+	}, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, []Result{
+		{Key: "key1"}, {Key: "key2"}, {Key: "key3", Err: ErrOverCapacity}, {Key: "key1"},
+	}, res)
+	assert.Equal(t, int32(2), c.cnt)
+}
+
+func TestNewBatchCache(t *testing.T) {
+	local := NewMapCache(time.Minute)
+	defer local.Close()
+	assert.Same(t, local, NewBatchCache(local))
+
+	// 隐藏掉原生的批量操作
+	c := NewBatchCache(struct{ Cache }{Cache: local})
+	_, ok := c.(*loopBatchCache)
+	require.True(t, ok)
+	ctx := context.Background()
+	res, err := c.SetMulti(ctx, []Entry{{Key: "key1", Val: 1}}, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, []Result{{Key: "key1"}}, res)
+	res, err = c.GetMulti(ctx, []string{"key1", "key2"})
+	require.NoError(t, err)
+	assert.Equal(t, []Result{
+		{Key: "key1", Val: 1},
+		{Key: "key2", Err: fmt.Errorf("%w, key: %s", ErrKeyNotFound, "key2")},
+	}, res)
+	res, err = c.DeleteMulti(ctx, []string{"key1"})
+	require.NoError(t, err)
+	assert.Equal(t, []Result{{Key: "key1"}}, res)
+}
+
+func TestRedisCache_GetMulti(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) redis.Cmdable
+		keys []string
+
+		wantRes []Result
+		wantErr error
+	}{
+		{
+			name: "get multi",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := mocks.NewMockCmdable(ctrl)
+				res := redis.NewSliceCmd(context.Background())
+				res.SetVal([]any{"val1", nil})
+				cmd.EXPECT().MGet(context.Background(), "key1", "key2").Return(res)
+				return cmd
+			},
+			keys: []string{"key1", "key2"},
+			wantRes: []Result{
+				{Key: "key1", Val: "val1"},
+				{Key: "key2", Err: fmt.Errorf("%w, key: %s", ErrKeyNotFound, "key2")},
+			},
+		},
+		{
+			name: "timeout",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := mocks.NewMockCmdable(ctrl)
+				res := redis.NewSliceCmd(context.Background())
+				res.SetErr(context.DeadlineExceeded)
+				cmd.EXPECT().MGet(context.Background(), "key1").Return(res)
+				return cmd
+			},
+			keys:    []string{"key1"},
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			c := NewRedisCache(tc.mock(ctrl))
+			res, err := c.GetMulti(context.Background(), tc.keys)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package gcache
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	//go:embed lua/bloom_add.lua
+	luaBloomAdd string
+
+	//go:embed lua/bloom_exists.lua
+	luaBloomExists string
+)
+
+// BloomFilter 判断 key 是否可能存在
+// MightContain 返回 false 时 key 一定不存在, 返回 true 时 key 有一定概率其实不存在
+type BloomFilter interface {
+	Add(ctx context.Context, key string) error
+	MightContain(ctx context.Context, key string) (bool, error)
+}
+
+// bloomParams 根据预计的元素个数和期望的误判率计算位数 m 和哈希函数个数 k
+func bloomParams(capacity uint64, fpRate float64) (m uint64, k uint64) {
+	if capacity == 0 {
+		capacity = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.01
+	}
+	m = uint64(math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	k = uint64(math.Round(float64(m) / float64(capacity) * math.Ln2))
+	if k == 0 {
+		k = 1
+	}
+	return m, k
+}
+
+// bloomLocations 使用双重哈希计算 key 对应的 k 个位置
+func bloomLocations(key string, m, k uint64) []uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	sum := h.Sum64()
+	h1, h2 := sum&0xffffffff, sum>>32
+	res := make([]uint64, k)
+	for i := range res {
+		res[i] = (h1 + uint64(i)*h2) % m
+	}
+	return res
+}
+
+// MemoryBloomFilter 进程内的布隆过滤器
+type MemoryBloomFilter struct {
+	mu   sync.RWMutex
+	bits []uint64
+	m    uint64
+	k    uint64
+}
+
+// NewMemoryBloomFilter capacity 是预计的元素个数, fpRate 是元素个数达到 capacity 时的误判率
+func NewMemoryBloomFilter(capacity uint64, fpRate float64) *MemoryBloomFilter {
+	m, k := bloomParams(capacity, fpRate)
+	return &MemoryBloomFilter{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+func (b *MemoryBloomFilter) Add(ctx context.Context, key string) error {
+	locs := bloomLocations(key, b.m, b.k)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, loc := range locs {
+		b.bits[loc/64] |= 1 << (loc % 64)
+	}
+	return nil
+}
+
+func (b *MemoryBloomFilter) MightContain(ctx context.Context, key string) (bool, error) {
+	locs := bloomLocations(key, b.m, b.k)
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, loc := range locs {
+		if b.bits[loc/64]&(1<<(loc%64)) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// RedisBloomFilter 使用 redis 的 bitmap 保存, 多个实例可以共享同一个过滤器
+// 所有实例需要使用相同的 capacity 和 fpRate, 否则计算出来的位置不一致
+type RedisBloomFilter struct {
+	client redis.Cmdable
+	key    string
+	m      uint64
+	k      uint64
+}
+
+func NewRedisBloomFilter(client redis.Cmdable, key string, capacity uint64, fpRate float64) *RedisBloomFilter {
+	m, k := bloomParams(capacity, fpRate)
+	return &RedisBloomFilter{
+		client: client,
+		key:    key,
+		m:      m,
+		k:      k,
+	}
+}
+
+func (b *RedisBloomFilter) Add(ctx context.Context, key string) error {
+	return b.client.Eval(ctx, luaBloomAdd, []string{b.key}, b.offsets(key)...).Err()
+}
+
+func (b *RedisBloomFilter) MightContain(ctx context.Context, key string) (bool, error) {
+	res, err := b.client.Eval(ctx, luaBloomExists, []string{b.key}, b.offsets(key)...).Int64()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+func (b *RedisBloomFilter) offsets(key string) []any {
+	locs := bloomLocations(key, b.m, b.k)
+	res := make([]any, len(locs))
+	for i, loc := range locs {
+		res[i] = loc
+	}
+	return res
+}
+
+// BloomFilterCache 在访问缓存之前先检查布隆过滤器, 一定不存在的 key 直接返回 ErrKeyNotFound,
+// 不会访问后面的缓存, 也不会触发 read through 的加载
+// 数据源中新增数据时需要调用 Filter.Add, 通过 Set 写入的 key 会自动加入过滤器
+type BloomFilterCache struct {
+	Cache
+	Filter BloomFilter
+}
+
+func (b *BloomFilterCache) Get(ctx context.Context, key string) (any, error) {
+	ok, err := b.Filter.MightContain(ctx, key)
+	// 过滤器出错时放行, 不能因为过滤器不可用导致所有的读都失败
+	if err == nil && !ok {
+		return nil, fmt.Errorf("%w, key: %s", ErrKeyNotFound, key)
+	}
+	return b.Cache.Get(ctx, key)
+}
+
+func (b *BloomFilterCache) Set(ctx context.Context, key string, val any, expiration time.Duration) error {
+	if err := b.Filter.Add(ctx, key); err != nil {
+		return err
+	}
+	return b.Cache.Set(ctx, key, val, expiration)
+}
@@ -0,0 +1,160 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/NotFound1911/gcache/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestBloomParams(t *testing.T) {
+	m, k := bloomParams(1000, 0.01)
+	assert.Equal(t, uint64(9586), m)
+	assert.Equal(t, uint64(7), k)
+	// 非法参数使用默认的误判率
+	m2, k2 := bloomParams(1000, 0)
+	assert.Equal(t, m, m2)
+	assert.Equal(t, k, k2)
+}
+
+func TestMemoryBloomFilter(t *testing.T) {
+	ctx := context.Background()
+	b := NewMemoryBloomFilter(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, b.Add(ctx, fmt.Sprintf("key_%d", i)))
+	}
+	for i := 0; i < 1000; i++ {
+		ok, err := b.MightContain(ctx, fmt.Sprintf("key_%d", i))
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+	fp := 0
+	for i := 0; i < 10000; i++ {
+		ok, err := b.MightContain(ctx, fmt.Sprintf("absent_%d", i))
+		require.NoError(t, err)
+		if ok {
+			fp++
+		}
+	}
+	assert.Less(t, fp, 300)
+}
+
+func TestRedisBloomFilter(t *testing.T) {
+	b := NewRedisBloomFilter(nil, "bloom", 1000, 0.01)
+	offsets := b.offsets("key1")
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) redis.Cmdable
+
+		wantOk  bool
+		wantErr error
+	}{
+		{
+			name: "might contain",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := mocks.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(1))
+				cmd.EXPECT().Eval(gomock.Any(), luaBloomExists, []string{"bloom"}, offsets...).Return(res)
+				return cmd
+			},
+			wantOk: true,
+		},
+		{
+			name: "not exist",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := mocks.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(0))
+				cmd.EXPECT().Eval(gomock.Any(), luaBloomExists, []string{"bloom"}, offsets...).Return(res)
+				return cmd
+			},
+		},
+		{
+			name: "eval error",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := mocks.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetErr(context.DeadlineExceeded)
+				cmd.EXPECT().Eval(gomock.Any(), luaBloomExists, []string{"bloom"}, offsets...).Return(res)
+				return cmd
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			b := NewRedisBloomFilter(tc.mock(ctrl), "bloom", 1000, 0.01)
+			ok, err := b.MightContain(context.Background(), "key1")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantOk, ok)
+		})
+	}
+}
+
+func TestBloomFilterCache_Get(t *testing.T) {
+	ctx := context.Background()
+	local := NewMapCache(time.Minute)
+	defer local.Close()
+	load := 0
+	c := &BloomFilterCache{
+		Cache: &ReadTroughCache{
+			Cache: local,
+			LoadFunc: func(ctx context.Context, key string) (any, error) {
+				load++
+				return "loaded", nil
+			},
+			Expiration: time.Minute,
+		},
+		Filter: NewMemoryBloomFilter(100, 0.01),
+	}
+	// 不在过滤器中的 key 不会触发加载
+	_, err := c.Get(ctx, "absent")
+	assert.Equal(t, fmt.Errorf("%w, key: %s", ErrKeyNotFound, "absent"), err)
+	assert.Equal(t, 0, load)
+
+	require.NoError(t, c.Filter.Add(ctx, "key1"))
+	val, err := c.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "loaded", val)
+	assert.Equal(t, 1, load)
+
+	// Set 的 key 会加入过滤器
+	require.NoError(t, c.Set(ctx, "key2", "set", time.Minute))
+	val, err = c.Get(ctx, "key2")
+	require.NoError(t, err)
+	assert.Equal(t, "set", val)
+	assert.Equal(t, 1, load)
+}
+
+type errBloomFilter struct{}
+
+func (errBloomFilter) Add(ctx context.Context, key string) error {
+	return errors.New("bloom unavailable")
+}
+
+func (errBloomFilter) MightContain(ctx context.Context, key string) (bool, error) {
+	return false, errors.New("bloom unavailable")
+}
+
+func TestBloomFilterCache_FilterError(t *testing.T) {
+	ctx := context.Background()
+	local := NewMapCache(time.Minute)
+	defer local.Close()
+	require.NoError(t, local.Set(ctx, "key1", "cached", time.Minute))
+	c := &BloomFilterCache{Cache: local, Filter: errBloomFilter{}}
+	// 过滤器不可用时放行
+	val, err := c.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "cached", val)
+	// 没有加入过滤器就不写缓存, 否则之后的 Get 会被挡住
+	assert.EqualError(t, c.Set(ctx, "key2", "val", time.Minute), "bloom unavailable")
+}
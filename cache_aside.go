@@ -0,0 +1,111 @@
+package gcache
+
+import (
+	"context"
+	"fmt"
+	"github.com/NotFound1911/gcache/clock"
+	"time"
+)
+
+type CacheAsideOption func(c *CacheAside)
+
+// CacheAside 先更新数据源再删除缓存
+// 删除缓存之后, 并发的读请求可能把更新之前读到的旧值重新写回缓存,
+// 配置 delay 之后会在 delay 之后再删除一次 (延迟双删)
+type CacheAside struct {
+	cache   Cache
+	delay   time.Duration
+	retry   func() RetryStrategy
+	onError func(key string, err error)
+	clock   clock.Clock
+}
+
+func NewCacheAside(c Cache, opts ...CacheAsideOption) *CacheAside {
+	res := &CacheAside{
+		cache: c,
+		retry: func() RetryStrategy {
+			return &FixedInterval{Interval: time.Millisecond * 100, MaxCnt: 3}
+		},
+		onError: func(key string, err error) {},
+		clock:   clock.New(),
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// BuildCacheAsideWithDelay 延迟双删的间隔, 应该大于一次读请求从数据源加载并写回缓存的耗时, 默认不开启
+func BuildCacheAsideWithDelay(delay time.Duration) CacheAsideOption {
+	return func(c *CacheAside) {
+		c.delay = delay
+	}
+}
+
+// BuildCacheAsideWithRetry 每次删除失败时调用 newRetry 创建新的重试策略, 默认间隔 100 毫秒重试 3 次
+func BuildCacheAsideWithRetry(newRetry func() RetryStrategy) CacheAsideOption {
+	return func(c *CacheAside) {
+		c.retry = newRetry
+	}
+}
+
+// BuildCacheAsideWithErrorHandler 延迟删除在后台进行, 重试之后依旧失败会交给 fn
+func BuildCacheAsideWithErrorHandler(fn func(key string, err error)) CacheAsideOption {
+	return func(c *CacheAside) {
+		c.onError = fn
+	}
+}
+
+func BuildCacheAsideWithClock(c clock.Clock) CacheAsideOption {
+	return func(ca *CacheAside) {
+		ca.clock = c
+	}
+}
+
+// Update 调用 update 更新数据源, 成功之后删除缓存
+// 数据源更新成功但是删除缓存失败时返回 ErrFailedToDeleteCache
+func (c *CacheAside) Update(ctx context.Context, key string, update func(ctx context.Context) error) error {
+	if err := update(ctx); err != nil {
+		return err
+	}
+	if err := c.delete(ctx, key); err != nil {
+		return err
+	}
+	if c.delay > 0 {
+		timer := c.clock.NewTimer(c.delay)
+		go func() {
+			defer timer.Stop()
+			<-timer.C()
+			if err := c.delete(context.Background(), key); err != nil {
+				c.onError(key, err)
+			}
+		}()
+	}
+	return nil
+}
+
+func (c *CacheAside) delete(ctx context.Context, key string) error {
+	retry := c.retry()
+	var timer clock.Timer
+	for {
+		err := c.cache.Delete(ctx, key)
+		if err == nil {
+			return nil
+		}
+		interval, ok := retry.Next()
+		if !ok {
+			return fmt.Errorf("%w, key: %s, 原因: %w", ErrFailedToDeleteCache, key, err)
+		}
+		if timer == nil {
+			timer = c.clock.NewTimer(interval)
+			defer timer.Stop()
+		} else {
+			timer.Reset(interval)
+		}
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"github.com/NotFound1911/gcache/clock/clocktest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyDeleteCache 前 fails 次 Delete 返回错误
+type flakyDeleteCache struct {
+	*MapCache
+	fails   atomic.Int32
+	deletes atomic.Int32
+}
+
+func (f *flakyDeleteCache) Delete(ctx context.Context, key string) error {
+	f.deletes.Add(1)
+	if f.fails.Add(-1) >= 0 {
+		return errors.New("redis error")
+	}
+	return f.MapCache.Delete(ctx, key)
+}
+
+func TestCacheAside_Update(t *testing.T) {
+	ctx := context.Background()
+	local := NewMapCache(time.Minute)
+	defer local.Close()
+	require.NoError(t, local.Set(ctx, "key1", "old", time.Minute))
+	c := NewCacheAside(local)
+
+	dbErr := errors.New("db error")
+	err := c.Update(ctx, "key1", func(ctx context.Context) error {
+		return dbErr
+	})
+	assert.Equal(t, dbErr, err)
+	// 数据源更新失败不删除缓存
+	val, err := local.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "old", val)
+
+	require.NoError(t, c.Update(ctx, "key1", func(ctx context.Context) error {
+		return nil
+	}))
+	_, err = local.Get(ctx, "key1")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestCacheAside_DelayedDoubleDelete(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktest.NewFakeClock(time.Now())
+	local := NewMapCache(time.Minute)
+	defer local.Close()
+	c := NewCacheAside(local, BuildCacheAsideWithDelay(time.Second), BuildCacheAsideWithClock(clk))
+	require.NoError(t, c.Update(ctx, "key1", func(ctx context.Context) error {
+		return nil
+	}))
+	// 并发的读请求把更新之前读到的旧值写回了缓存
+	require.NoError(t, local.Set(ctx, "key1", "stale", time.Minute))
+	clk.BlockUntil(1)
+	clk.Advance(time.Second)
+	require.Eventually(t, func() bool {
+		_, err := local.Get(ctx, "key1")
+		return errors.Is(err, ErrKeyNotFound)
+	}, time.Second, time.Millisecond)
+}
+
+func TestCacheAside_Retry(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktest.NewFakeClock(time.Now())
+	local := NewMapCache(time.Minute)
+	defer local.Close()
+	require.NoError(t, local.Set(ctx, "key1", "old", time.Minute))
+	flaky := &flakyDeleteCache{MapCache: local}
+	flaky.fails.Store(1)
+	c := NewCacheAside(flaky, BuildCacheAsideWithClock(clk), BuildCacheAsideWithRetry(func() RetryStrategy {
+		return &FixedInterval{Interval: time.Second, MaxCnt: 1}
+	}))
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.Update(ctx, "key1", func(ctx context.Context) error {
+			return nil
+		})
+	}()
+	clk.BlockUntil(1)
+	clk.Advance(time.Second)
+	require.NoError(t, <-errCh)
+	assert.Equal(t, int32(2), flaky.deletes.Load())
+	_, err := local.Get(ctx, "key1")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	// 超出重试次数
+	flaky.fails.Store(2)
+	go func() {
+		errCh <- c.Update(ctx, "key1", func(ctx context.Context) error {
+			return nil
+		})
+	}()
+	clk.BlockUntil(1)
+	clk.Advance(time.Second)
+	err = <-errCh
+	assert.ErrorIs(t, err, ErrFailedToDeleteCache)
+}
+
+func TestCacheAside_DelayedDeleteError(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktest.NewFakeClock(time.Now())
+	local := NewMapCache(time.Minute)
+	defer local.Close()
+	flaky := &flakyDeleteCache{MapCache: local}
+	errCh := make(chan error, 1)
+	c := NewCacheAside(flaky, BuildCacheAsideWithClock(clk), BuildCacheAsideWithDelay(time.Second),
+		BuildCacheAsideWithRetry(func() RetryStrategy {
+			return &FixedInterval{}
+		}),
+		BuildCacheAsideWithErrorHandler(func(key string, err error) {
+			errCh <- err
+		}))
+	require.NoError(t, c.Update(ctx, "key1", func(ctx context.Context) error {
+		return nil
+	}))
+	flaky.fails.Store(1)
+	clk.BlockUntil(1)
+	clk.Advance(time.Second)
+	assert.ErrorIs(t, <-errCh, ErrFailedToDeleteCache)
+}
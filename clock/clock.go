@@ -0,0 +1,56 @@
+// Package clock 抽象时间相关的操作, 方便在测试中控制时间
+package clock
+
+import "time"
+
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+	NewTimer(d time.Duration) Timer
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// New 返回使用真实时间的 Clock
+func New() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{Ticker: time.NewTicker(d)}
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{Timer: time.NewTimer(d)}
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (r realTicker) C() <-chan time.Time {
+	return r.Ticker.C
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (r realTimer) C() <-chan time.Time {
+	return r.Timer.C
+}
@@ -0,0 +1,134 @@
+// Package clocktest 提供可以手动推进时间的 Clock, 用于测试过期和重试
+package clocktest
+
+import (
+	"github.com/NotFound1911/gcache/clock"
+	"sync"
+	"time"
+)
+
+var _ clock.Clock = (*FakeClock)(nil)
+
+// FakeClock 只有调用 Advance 时间才会前进, 到期的 Ticker 和 Timer 会在 Advance 中触发
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters map[*fakeWaiter]struct{}
+}
+
+type fakeWaiter struct {
+	deadline time.Time
+	period   time.Duration // 为 0 表示 Timer
+	ch       chan time.Time
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	res := &FakeClock{
+		now:     now,
+		waiters: make(map[*fakeWaiter]struct{}),
+	}
+	res.cond = sync.NewCond(&res.mu)
+	return res
+}
+
+func (f *FakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Advance 推进时间, 并触发所有到期的 Ticker 和 Timer
+// 和 time.Ticker 一样, 接收方来不及处理的 tick 会被丢弃
+func (f *FakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	for w := range f.waiters {
+		for !w.deadline.After(f.now) {
+			select {
+			case w.ch <- f.now:
+			default:
+			}
+			if w.period <= 0 {
+				delete(f.waiters, w)
+				break
+			}
+			w.deadline = w.deadline.Add(w.period)
+		}
+	}
+}
+
+// BlockUntil 阻塞直到有 n 个正在等待的 Ticker 和 Timer
+// 用于确认被测试的协程已经开始等待, 再推进时间
+func (f *FakeClock) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.waiters) < n {
+		f.cond.Wait()
+	}
+}
+
+func (f *FakeClock) NewTicker(d time.Duration) clock.Ticker {
+	if d <= 0 {
+		panic("clocktest: ticker 的间隔必须大于 0")
+	}
+	w := &fakeWaiter{period: d, ch: make(chan time.Time, 1)}
+	f.add(w, d)
+	return &fakeTicker{clock: f, w: w}
+}
+
+func (f *FakeClock) NewTimer(d time.Duration) clock.Timer {
+	w := &fakeWaiter{ch: make(chan time.Time, 1)}
+	f.add(w, d)
+	return &fakeTimer{clock: f, w: w}
+}
+
+func (f *FakeClock) add(w *fakeWaiter, d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.deadline = f.now.Add(d)
+	f.waiters[w] = struct{}{}
+	f.cond.Broadcast()
+}
+
+// remove 返回 w 是否还在等待
+func (f *FakeClock) remove(w *fakeWaiter) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.waiters[w]
+	delete(f.waiters, w)
+	return ok
+}
+
+type fakeTicker struct {
+	clock *FakeClock
+	w     *fakeWaiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.w.ch
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.remove(t.w)
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	w     *fakeWaiter
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.w.ch
+}
+
+func (t *fakeTimer) Stop() bool {
+	return t.clock.remove(t.w)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	active := t.clock.remove(t.w)
+	t.clock.add(t.w, d)
+	return active
+}
@@ -0,0 +1,36 @@
+package clocktest
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := NewFakeClock(start)
+	ticker := clk.NewTicker(time.Second)
+	timer := clk.NewTimer(2 * time.Second)
+
+	clk.Advance(500 * time.Millisecond)
+	assert.Equal(t, start.Add(500*time.Millisecond), clk.Now())
+	assert.Len(t, ticker.C(), 0)
+
+	clk.Advance(time.Second)
+	assert.Equal(t, start.Add(1500*time.Millisecond), <-ticker.C())
+	assert.Len(t, timer.C(), 0)
+
+	clk.Advance(time.Second)
+	assert.Equal(t, start.Add(2500*time.Millisecond), <-timer.C())
+	assert.False(t, timer.Stop())
+	assert.False(t, timer.Reset(time.Second))
+	assert.True(t, timer.Stop())
+
+	// 没有及时接收的 tick 会被丢弃
+	clk.Advance(3 * time.Second)
+	assert.Len(t, ticker.C(), 1)
+	ticker.Stop()
+	<-ticker.C()
+	clk.Advance(time.Second)
+	assert.Len(t, ticker.C(), 0)
+}
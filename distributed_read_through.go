@@ -0,0 +1,146 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type DistributedReadThroughOption func(d *DistributedReadThroughCache)
+
+// DistributedReadThroughCache 跨进程的 singleflight
+// 未命中时先在进程内合并, 再通过分布式锁保证同一个 key 只有一个实例去加载,
+// 没有抢到锁的实例轮询缓存等待结果, 锁被释放但是缓存中没有值的时候抢锁加载, 超时之后自己直接加载
+type DistributedReadThroughCache struct {
+	*ReadTroughCache
+	client         *Client
+	lockPrefix     string
+	lockExpiration time.Duration
+	pollInterval   time.Duration
+	waitTimeout    time.Duration
+}
+
+// NewDistributedReadThroughCache c 一般是多个实例共享的 RedisCache, 分布式锁使用 client
+func NewDistributedReadThroughCache(c Cache, client *Client, loadFunc func(ctx context.Context, key string) (any, error),
+	expiration time.Duration, opts ...DistributedReadThroughOption) *DistributedReadThroughCache {
+	res := &DistributedReadThroughCache{
+		ReadTroughCache: &ReadTroughCache{
+			Cache:      c,
+			LoadFunc:   loadFunc,
+			Expiration: expiration,
+		},
+		client:         client,
+		lockPrefix:     "gcache:load:",
+		lockExpiration: time.Second * 10,
+		pollInterval:   time.Millisecond * 50,
+		waitTimeout:    time.Second * 3,
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// BuildDistributedReadThroughWithLockPrefix 锁的 key 为 prefix + key, 默认 gcache:load:
+func BuildDistributedReadThroughWithLockPrefix(prefix string) DistributedReadThroughOption {
+	return func(d *DistributedReadThroughCache) {
+		d.lockPrefix = prefix
+	}
+}
+
+// BuildDistributedReadThroughWithLockExpiration 锁的过期时间, 默认 10 秒
+// 加载期间每隔三分之一的过期时间续约一次, 续约失败的话其他实例可能同时加载
+func BuildDistributedReadThroughWithLockExpiration(expiration time.Duration) DistributedReadThroughOption {
+	return func(d *DistributedReadThroughCache) {
+		d.lockExpiration = expiration
+	}
+}
+
+// BuildDistributedReadThroughWithWait 没有抢到锁时轮询缓存的间隔和最长等待时间, 默认 50 毫秒和 3 秒
+func BuildDistributedReadThroughWithWait(pollInterval, timeout time.Duration) DistributedReadThroughOption {
+	return func(d *DistributedReadThroughCache) {
+		d.pollInterval = pollInterval
+		d.waitTimeout = timeout
+	}
+}
+
+func (d *DistributedReadThroughCache) Get(ctx context.Context, key string) (any, error) {
+	val, miss, err := d.cached(ctx, key)
+	if miss {
+		val, err = loadShared(ctx, &d.g, &d.stats, key, d.LoadTimeout, func(ctx context.Context) (any, error) {
+			return d.loadDistributed(ctx, key)
+		})
+	}
+	return val, err
+}
+
+// loadDistributed 抢到锁的实例负责加载, 其他实例等待
+func (d *DistributedReadThroughCache) loadDistributed(ctx context.Context, key string) (any, error) {
+	lock, err := d.client.TryLock(ctx, d.lockPrefix+key, d.lockExpiration)
+	switch {
+	case err == nil:
+		return d.loadLocked(ctx, lock, key)
+	case errors.Is(err, ErrFailedToPreemptLock):
+		return d.wait(ctx, key)
+	default:
+		// 锁不可用的时候退化成直接加载
+		return d.load(ctx, key)
+	}
+}
+
+// loadLocked 持有锁的时候加载, 加载期间自动续约, 解锁之后停止续约
+func (d *DistributedReadThroughCache) loadLocked(ctx context.Context, lock *Lock, key string) (any, error) {
+	defer func() {
+		// 解锁失败的话等锁过期
+		_ = lock.Unlock(ctx)
+	}()
+	// 抢锁期间其他实例可能已经加载完成
+	val, miss, err := d.cached(ctx, key)
+	if !miss {
+		return val, err
+	}
+	go func() {
+		// 续约失败说明锁已经过期, 其他实例可能会同时加载
+		interval := d.lockExpiration / 3
+		_ = lock.AutoRefresh(interval, interval)
+	}()
+	return d.load(ctx, key)
+}
+
+// wait 轮询缓存等待其他实例加载完成, 超时之后自己加载
+// 持有锁的实例加载失败或者没有写入缓存的时候会直接释放锁, 所以每次没有命中都重新抢锁, 抢到就自己加载
+func (d *DistributedReadThroughCache) wait(ctx context.Context, key string) (any, error) {
+	clk := d.client.clock
+	deadline := clk.Now().Add(d.waitTimeout)
+	timer := clk.NewTimer(d.pollInterval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		val, miss, err := d.cached(ctx, key)
+		if !miss {
+			return val, err
+		}
+		lock, err := d.client.TryLock(ctx, d.lockPrefix+key, d.lockExpiration)
+		if err == nil {
+			return d.loadLocked(ctx, lock, key)
+		}
+		if !errors.Is(err, ErrFailedToPreemptLock) || !clk.Now().Before(deadline) {
+			return d.load(ctx, key)
+		}
+		timer.Reset(d.pollInterval)
+	}
+}
+
+// cached 读取缓存, miss 表示需要加载, 命中负缓存不需要加载
+func (d *DistributedReadThroughCache) cached(ctx context.Context, key string) (val any, miss bool, err error) {
+	val, err = d.Cache.Get(ctx, key)
+	if err == nil && isTombstone(val) {
+		return nil, false, fmt.Errorf("%w, key: %s", ErrKeyNotFound, key)
+	}
+	return val, errors.Is(err, ErrKeyNotFound), err
+}
@@ -0,0 +1,200 @@
+package gcache
+
+import (
+	"context"
+	"github.com/NotFound1911/gcache/clock/clocktest"
+	"github.com/NotFound1911/gcache/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestDistributedReadThroughCache_Get(t *testing.T) {
+	const lockKey = "gcache:load:key1"
+	testCases := []struct {
+		name string
+		// local 是多个实例共享的缓存
+		mock func(ctrl *gomock.Controller, local *MapCache) redis.Cmdable
+		// advance 在等待期间模拟其他实例和时间的推进
+		advance func(clk *clocktest.FakeClock, local *MapCache)
+
+		wantVal  any
+		wantLoad int
+	}{
+		{
+			name: "lock and load",
+			mock: func(ctrl *gomock.Controller, local *MapCache) redis.Cmdable {
+				cmd := mocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().SetNX(gomock.Any(), lockKey, gomock.Any(), 10*time.Second).
+					Return(redis.NewBoolResult(true, nil))
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(1))
+				cmd.EXPECT().Eval(gomock.Any(), luaUnlock, []string{lockKey}, gomock.Any()).Return(res)
+				return cmd
+			},
+			wantVal:  "loaded",
+			wantLoad: 1,
+		},
+		{
+			name: "loaded by other after lock",
+			mock: func(ctrl *gomock.Controller, local *MapCache) redis.Cmdable {
+				cmd := mocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().SetNX(gomock.Any(), lockKey, gomock.Any(), 10*time.Second).
+					DoAndReturn(func(ctx context.Context, key string, val any, expiration time.Duration) *redis.BoolCmd {
+						require.NoError(t, local.Set(ctx, "key1", "other", time.Minute))
+						return redis.NewBoolResult(true, nil)
+					})
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(1))
+				cmd.EXPECT().Eval(gomock.Any(), luaUnlock, []string{lockKey}, gomock.Any()).Return(res)
+				return cmd
+			},
+			wantVal: "other",
+		},
+		{
+			name: "lock error",
+			mock: func(ctrl *gomock.Controller, local *MapCache) redis.Cmdable {
+				cmd := mocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().SetNX(gomock.Any(), lockKey, gomock.Any(), 10*time.Second).
+					Return(redis.NewBoolResult(false, context.DeadlineExceeded))
+				return cmd
+			},
+			wantVal:  "loaded",
+			wantLoad: 1,
+		},
+		{
+			name: "wait for other",
+			mock: func(ctrl *gomock.Controller, local *MapCache) redis.Cmdable {
+				cmd := mocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().SetNX(gomock.Any(), lockKey, gomock.Any(), 10*time.Second).
+					Return(redis.NewBoolResult(false, nil)).Times(2)
+				return cmd
+			},
+			advance: func(clk *clocktest.FakeClock, local *MapCache) {
+				clk.BlockUntil(1)
+				clk.Advance(time.Second)
+				clk.BlockUntil(1)
+				require.NoError(t, local.Set(context.Background(), "key1", "other", time.Minute))
+				clk.Advance(time.Second)
+			},
+			wantVal: "other",
+		},
+		{
+			name: "wait timeout",
+			mock: func(ctrl *gomock.Controller, local *MapCache) redis.Cmdable {
+				cmd := mocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().SetNX(gomock.Any(), lockKey, gomock.Any(), 10*time.Second).
+					Return(redis.NewBoolResult(false, nil)).Times(4)
+				return cmd
+			},
+			advance: func(clk *clocktest.FakeClock, local *MapCache) {
+				for i := 0; i < 3; i++ {
+					clk.BlockUntil(1)
+					clk.Advance(time.Second)
+				}
+			},
+			wantVal:  "loaded",
+			wantLoad: 1,
+		},
+		{
+			// 持有锁的实例没有写入缓存就释放了锁, 不用等到超时
+			name: "released without value",
+			mock: func(ctrl *gomock.Controller, local *MapCache) redis.Cmdable {
+				cmd := mocks.NewMockCmdable(ctrl)
+				gomock.InOrder(
+					cmd.EXPECT().SetNX(gomock.Any(), lockKey, gomock.Any(), 10*time.Second).
+						Return(redis.NewBoolResult(false, nil)),
+					cmd.EXPECT().SetNX(gomock.Any(), lockKey, gomock.Any(), 10*time.Second).
+						Return(redis.NewBoolResult(true, nil)),
+				)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(1))
+				cmd.EXPECT().Eval(gomock.Any(), luaUnlock, []string{lockKey}, gomock.Any()).Return(res)
+				return cmd
+			},
+			advance: func(clk *clocktest.FakeClock, local *MapCache) {
+				clk.BlockUntil(1)
+				clk.Advance(time.Second)
+			},
+			wantVal:  "loaded",
+			wantLoad: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			local := NewMapCache(time.Minute)
+			defer local.Close()
+			clk := clocktest.NewFakeClock(time.Now())
+			load := 0
+			c := NewDistributedReadThroughCache(local, NewClient(tc.mock(ctrl, local), BuildClientWithClock(clk)),
+				func(ctx context.Context, key string) (any, error) {
+					load++
+					return "loaded", nil
+				}, time.Minute, BuildDistributedReadThroughWithWait(time.Second, 3*time.Second))
+			if tc.advance != nil {
+				go tc.advance(clk, local)
+			}
+			val, err := c.Get(context.Background(), "key1")
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantVal, val)
+			assert.Equal(t, tc.wantLoad, load)
+			val, err = local.Get(context.Background(), "key1")
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantVal, val)
+		})
+	}
+}
+
+func TestDistributedReadThroughCache_Tombstone(t *testing.T) {
+	local := NewMapCache(time.Minute)
+	defer local.Close()
+	require.NoError(t, local.Set(context.Background(), "key1", tombstone, time.Minute))
+	// 命中负缓存不会抢锁也不会加载
+	c := NewDistributedReadThroughCache(local, NewClient(nil), func(ctx context.Context, key string) (any, error) {
+		t.Fatal("unexpected load")
+		return nil, nil
+	}, time.Minute)
+	_, err := c.Get(context.Background(), "key1")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestDistributedReadThroughCache_RefreshLock(t *testing.T) {
+	const lockKey = "gcache:load:key1"
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	local := NewMapCache(time.Minute)
+	defer local.Close()
+	clk := clocktest.NewFakeClock(time.Now())
+
+	refreshed := make(chan struct{})
+	cmd := mocks.NewMockCmdable(ctrl)
+	cmd.EXPECT().SetNX(gomock.Any(), lockKey, gomock.Any(), 3*time.Second).
+		Return(redis.NewBoolResult(true, nil))
+	cmd.EXPECT().Eval(gomock.Any(), luaRefresh, []string{lockKey}, gomock.Any(), float64(3)).
+		DoAndReturn(func(ctx context.Context, script string, keys []string, args ...any) *redis.Cmd {
+			close(refreshed)
+			res := redis.NewCmd(ctx)
+			res.SetVal(int64(1))
+			return res
+		})
+	res := redis.NewCmd(context.Background())
+	res.SetVal(int64(1))
+	cmd.EXPECT().Eval(gomock.Any(), luaUnlock, []string{lockKey}, gomock.Any()).Return(res)
+
+	// 加载的耗时超过锁的过期时间, 加载期间续约
+	c := NewDistributedReadThroughCache(local, NewClient(cmd, BuildClientWithClock(clk)),
+		func(ctx context.Context, key string) (any, error) {
+			clk.BlockUntil(1)
+			clk.Advance(time.Second)
+			<-refreshed
+			return "loaded", nil
+		}, time.Minute, BuildDistributedReadThroughWithLockExpiration(3*time.Second))
+	val, err := c.Get(context.Background(), "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "loaded", val)
+}
@@ -0,0 +1,127 @@
+package gcache
+
+import (
+	"context"
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// envelopeMagic 编码后的 envelope 的前缀, 用来和普通的值区分
+const envelopeMagic = "\x00gcache:env:"
+
+// envelope 装饰器需要和值一起保存在缓存中的元数据, 例如 XFetchCache 的加载耗时
+// MapCache 中直接保存 *envelope, 值的类型保持不变;
+// RedisCache 中保存 MarshalBinary 编码后的字节, 格式为 envelopeMagic + 8 字节元数据 + 值.
+// 值按照 redis 客户端的规则转换成字节, 所以从 redis 读出来的值和 RedisCache.Get 一样是 string.
+// 绕过装饰器直接读取缓存的调用方读到的是 *envelope 或者编码后的字符串
+type envelope struct {
+	val  any
+	meta uint64
+}
+
+func (e *envelope) MarshalBinary() ([]byte, error) {
+	res := make([]byte, 0, len(envelopeMagic)+8+16)
+	res = append(res, envelopeMagic...)
+	res = binary.BigEndian.AppendUint64(res, e.meta)
+	return appendValue(res, e.val)
+}
+
+// appendValue 把值转换成字节, 和 redis 客户端处理值的方式一致
+func appendValue(res []byte, val any) ([]byte, error) {
+	switch v := val.(type) {
+	case string:
+		res = append(res, v...)
+	case []byte:
+		res = append(res, v...)
+	case encoding.BinaryMarshaler:
+		data, err := v.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, data...)
+	default:
+		res = fmt.Append(res, v)
+	}
+	return res, nil
+}
+
+// decodeEnvelope 不是 envelope 的值当作没有元数据处理
+func decodeEnvelope(val any) *envelope {
+	switch v := val.(type) {
+	case *envelope:
+		return v
+	case string:
+		if len(v) >= len(envelopeMagic)+8 && strings.HasPrefix(v, envelopeMagic) {
+			data := v[len(envelopeMagic):]
+			return &envelope{val: data[8:], meta: binary.BigEndian.Uint64([]byte(data[:8]))}
+		}
+	}
+	return &envelope{val: val}
+}
+
+func unwrapEnvelope(val any) any {
+	if e, ok := val.(*envelope); ok {
+		return e.val
+	}
+	return val
+}
+
+// envelopeCache 写入的时候包装成 envelope, 读取的时候解开, 剩余过期时间由底层缓存提供
+type envelopeCache struct {
+	TTLCache
+}
+
+func (c *envelopeCache) Set(ctx context.Context, key string, val any, expiration time.Duration) error {
+	return c.TTLCache.Set(ctx, key, toEnvelope(val), expiration)
+}
+
+func (c *envelopeCache) setAdjusted(ctx context.Context, key string, val any, expiration time.Duration) error {
+	return setAdjusted(ctx, c.TTLCache, key, toEnvelope(val), expiration)
+}
+
+// toEnvelope 直接写入的值没有元数据
+func toEnvelope(val any) *envelope {
+	if e, ok := val.(*envelope); ok {
+		return e
+	}
+	return &envelope{val: val}
+}
+
+func (c *envelopeCache) Get(ctx context.Context, key string) (any, error) {
+	val, err := c.TTLCache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return decodeEnvelope(val).val, nil
+}
+
+func (c *envelopeCache) GetWithTTL(ctx context.Context, key string) (any, time.Duration, error) {
+	e, ttl, err := c.getEnvelope(ctx, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	return e.val, ttl, nil
+}
+
+func (c *envelopeCache) getEnvelope(ctx context.Context, key string) (*envelope, time.Duration, error) {
+	val, ttl, err := c.TTLCache.GetWithTTL(ctx, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	return decodeEnvelope(val), ttl, nil
+}
+
+func (c *envelopeCache) LoadAndDelete(ctx context.Context, key string) (any, error) {
+	val, err := c.TTLCache.LoadAndDelete(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return decodeEnvelope(val).val, nil
+}
+
+func (c *envelopeCache) unwrap() Cache {
+	return c.TTLCache
+}
@@ -0,0 +1,78 @@
+package gcache
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestEnvelope_Binary(t *testing.T) {
+	testCases := []struct {
+		name string
+		val  any
+
+		wantVal any
+	}{
+		{
+			name:    "string",
+			val:     "val1",
+			wantVal: "val1",
+		},
+		{
+			name:    "bytes",
+			val:     []byte("val1"),
+			wantVal: "val1",
+		},
+		{
+			name:    "int",
+			val:     123,
+			wantVal: "123",
+		},
+		{
+			name:    "tombstone",
+			val:     tombstone,
+			wantVal: tombstoneEncoding,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := (&envelope{val: tc.val, meta: 20}).MarshalBinary()
+			require.NoError(t, err)
+			// redis 读出来的是 string
+			e := decodeEnvelope(string(data))
+			assert.Equal(t, tc.wantVal, e.val)
+			assert.Equal(t, uint64(20), e.meta)
+		})
+	}
+	// 普通的值没有元数据
+	e := decodeEnvelope("plain")
+	assert.Equal(t, "plain", e.val)
+	assert.Equal(t, uint64(0), e.meta)
+}
+
+func TestEnvelopeCache(t *testing.T) {
+	local := NewMapCache(time.Minute)
+	defer local.Close()
+	c := &envelopeCache{TTLCache: local}
+	ctx := context.Background()
+	require.NoError(t, c.Set(ctx, "key1", 123, time.Minute))
+	require.NoError(t, c.Set(ctx, "key2", &envelope{val: "val2", meta: 20}, 0))
+
+	// MapCache 中的值保持原来的类型
+	val, err := c.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, 123, val)
+	e, ttl, err := c.getEnvelope(ctx, "key2")
+	require.NoError(t, err)
+	assert.Equal(t, &envelope{val: "val2", meta: 20}, e)
+	assert.Equal(t, NoExpiration, ttl)
+	val, err = c.LoadAndDelete(ctx, "key2")
+	require.NoError(t, err)
+	assert.Equal(t, "val2", val)
+	// 统计数据来自底层缓存
+	sp, ok := statsProviderOf(c)
+	require.True(t, ok)
+	assert.Equal(t, int64(2), sp.Stats().Sets)
+}
@@ -0,0 +1,34 @@
+package gcache
+
+import "errors"
+
+// 所有缓存实现共用的错误, 调用方应该使用 errors.Is 判断
+var (
+	// ErrKeyNotFound 缓存未命中, 各个实现都会把自己的未命中错误转换成它
+	ErrKeyNotFound = errors.New("gcache 键不存在")
+	// ErrOverCapacity 超过容量限制, 并且淘汰策略无法腾出空间
+	ErrOverCapacity = errors.New("gcache 超过容量限制")
+	// ErrCacheClosed 缓存已经关闭
+	ErrCacheClosed = errors.New("gcache 缓存已关闭")
+	// ErrTypeMismatch 缓存中的值的类型和期望的不一致
+	ErrTypeMismatch = errors.New("gcache 值的类型不匹配")
+	// ErrUnknownSize 无法计算值占用的大小
+	ErrUnknownSize = errors.New("gcache 无法计算值的大小")
+	// ErrFailedToSetCache 写入缓存失败
+	ErrFailedToSetCache = errors.New("gcache: 写入 redis 失败")
+	// ErrFailedToRefreshCache 加载成功之后写回缓存失败
+	ErrFailedToRefreshCache = errors.New("gcache 刷新缓存失败")
+	// ErrFailedToWriteBack 写回数据源失败
+	ErrFailedToWriteBack = errors.New("gcache 写回失败")
+	// ErrFailedToDeleteCache 更新数据源之后删除缓存失败
+	ErrFailedToDeleteCache = errors.New("gcache 删除缓存失败")
+	// ErrSharedEvictionPolicy ShardedMapCache 的多个分片使用了同一个淘汰策略实例
+	ErrSharedEvictionPolicy = errors.New("gcache 分片不能共享同一个淘汰策略")
+	// ErrNotExist LoadFunc 在数据源中找不到数据时返回, read through 缓存会把它记录为负缓存
+	ErrNotExist = errors.New("gcache 数据不存在")
+)
+
+// IsKeyNotFound 判断是否是缓存未命中
+func IsKeyNotFound(err error) bool {
+	return errors.Is(err, ErrKeyNotFound)
+}
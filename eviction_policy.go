@@ -0,0 +1,53 @@
+package gcache
+
+// EvictionPolicy 淘汰策略, 由 MapCache 在持有锁时调用
+// OnGet 是在读锁下调用的, 所以实现需要自己保证并发安全
+// 容量限制由 MaxCntCache 负责, 超过容量时通过 Victim 挑选需要淘汰的 key
+type EvictionPolicy interface {
+	// OnSet key 被写入或者更新
+	OnSet(key string)
+	// OnGet key 被命中
+	OnGet(key string)
+	// OnDelete key 被删除, 包括主动删除, 过期和淘汰
+	OnDelete(key string)
+	// Victim 返回应该被淘汰的 key, 返回的 key 必须还在缓存中
+	// 没有可淘汰的 key 则返回 false, 此时写入会返回 ErrOverCapacity
+	Victim() (string, bool)
+}
+
+// nopPolicy 默认策略, 不做任何淘汰, 容量满了之后拒绝写入
+type nopPolicy struct{}
+
+func (nopPolicy) OnSet(key string) {}
+
+func (nopPolicy) OnGet(key string) {}
+
+func (nopPolicy) OnDelete(key string) {}
+
+func (nopPolicy) Victim() (string, bool) {
+	return "", false
+}
+
+// BuildMapCacheWithEvictionPolicy 使用自定义的淘汰策略, 传入 nil 则使用默认策略
+// 淘汰策略只受单个 MapCache 的锁保护, 不能在多个 MapCache 之间共享,
+// 用在多个分片的 ShardedMapCache 上会返回 ErrSharedEvictionPolicy, 需要使用 BuildMapCacheWithEvictionPolicyFactory
+func BuildMapCacheWithEvictionPolicy(p EvictionPolicy) MapCacheOption {
+	return func(cache *MapCache) {
+		if p == nil {
+			cache.policy = nopPolicy{}
+			cache.sharedPolicy = false
+			return
+		}
+		cache.policy = p
+		cache.sharedPolicy = true
+	}
+}
+
+// BuildMapCacheWithEvictionPolicyFactory 每个 MapCache 使用 factory 创建自己的淘汰策略
+// 用在 ShardedMapCache 上时每个分片都有独立的淘汰策略
+func BuildMapCacheWithEvictionPolicyFactory(factory func() EvictionPolicy) MapCacheOption {
+	return func(cache *MapCache) {
+		BuildMapCacheWithEvictionPolicy(factory())(cache)
+		cache.sharedPolicy = false
+	}
+}
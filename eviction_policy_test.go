@@ -0,0 +1,84 @@
+package gcache
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// fifoPolicy 先进先出, 用来验证自定义淘汰策略
+type fifoPolicy struct {
+	keys []string
+}
+
+func (f *fifoPolicy) OnSet(key string) {
+	for _, k := range f.keys {
+		if k == key {
+			return
+		}
+	}
+	f.keys = append(f.keys, key)
+}
+
+func (f *fifoPolicy) OnGet(key string) {}
+
+func (f *fifoPolicy) OnDelete(key string) {
+	for i, k := range f.keys {
+		if k == key {
+			f.keys = append(f.keys[:i], f.keys[i+1:]...)
+			return
+		}
+	}
+}
+
+func (f *fifoPolicy) Victim() (string, bool) {
+	if len(f.keys) == 0 {
+		return "", false
+	}
+	return f.keys[0], true
+}
+
+func TestBuildMapCacheWithEvictionPolicy(t *testing.T) {
+	testCases := []struct {
+		name   string
+		policy EvictionPolicy
+
+		wantErr     error
+		wantEvicted []string
+	}{
+		{
+			name:    "default policy",
+			wantErr: ErrOverCapacity,
+		},
+		{
+			name:        "custom policy",
+			policy:      &fifoPolicy{},
+			wantEvicted: []string{"key1"},
+		},
+		{
+			name:        "lru policy",
+			policy:      NewLRUPolicy(),
+			wantEvicted: []string{"key2"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var evicted []string
+			c := NewMaxCntCache(NewMapCache(time.Minute, BuildMapCacheWithEvictionPolicy(tc.policy),
+				BuildMapCacheWithEvictedCallback(func(key string, val any) {
+					evicted = append(evicted, key)
+				})), 2)
+			defer c.Close()
+			ctx := context.Background()
+			require.NoError(t, c.Set(ctx, "key1", 1, time.Minute))
+			require.NoError(t, c.Set(ctx, "key2", 2, time.Minute))
+			_, err := c.Get(ctx, "key1")
+			require.NoError(t, err)
+			err = c.Set(ctx, "key3", 3, time.Minute)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantEvicted, evicted)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package gcache
+
+// EvictionReason 数据被移出缓存的原因
+type EvictionReason uint8
+
+const (
+	// EvictionDeleted 调用 Delete 或者 LoadAndDelete 主动删除
+	EvictionDeleted EvictionReason = iota + 1
+	// EvictionExpired 过期被清理
+	EvictionExpired
+	// EvictionCapacity 超过容量被淘汰策略淘汰
+	EvictionCapacity
+	// EvictionReplaced 被同一个 key 的新值覆盖
+	EvictionReplaced
+	// EvictionClosed 缓存关闭时被清空
+	EvictionClosed
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case EvictionDeleted:
+		return "deleted"
+	case EvictionExpired:
+		return "expired"
+	case EvictionCapacity:
+		return "capacity"
+	case EvictionReplaced:
+		return "replaced"
+	case EvictionClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
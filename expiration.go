@@ -0,0 +1,127 @@
+package gcache
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// ExpirationStrategy 根据 key 和配置的过期时间计算写入缓存时实际使用的过期时间
+// 大量 key 使用相同的过期时间会在同一时刻一起过期, 可以用它把过期时间打散
+type ExpirationStrategy interface {
+	Expiration(key string, expiration time.Duration) time.Duration
+}
+
+// ExpirationFunc 按 key 计算过期时间
+type ExpirationFunc func(key string, expiration time.Duration) time.Duration
+
+func (f ExpirationFunc) Expiration(key string, expiration time.Duration) time.Duration {
+	return f(key, expiration)
+}
+
+// FixedExpiration 直接使用配置的过期时间
+type FixedExpiration struct{}
+
+func (FixedExpiration) Expiration(key string, expiration time.Duration) time.Duration {
+	return expiration
+}
+
+// JitterExpiration 在配置的过期时间上随机增加或者减少 ratio 比例
+type JitterExpiration struct {
+	ratio float64
+}
+
+// NewJitterExpiration ratio 取值 (0, 1), 例如 0.1 表示在 ±10% 的范围内随机
+func NewJitterExpiration(ratio float64) *JitterExpiration {
+	if ratio < 0 {
+		ratio = 0
+	}
+	if ratio >= 1 {
+		ratio = 0.99
+	}
+	return &JitterExpiration{ratio: ratio}
+}
+
+func (j *JitterExpiration) Expiration(key string, expiration time.Duration) time.Duration {
+	// 不过期的不需要打散
+	if expiration <= 0 {
+		return expiration
+	}
+	delta := time.Duration(float64(expiration) * j.ratio * (2*rand.Float64() - 1))
+	return expiration + delta
+}
+
+// expirationOf strategy 为 nil 时使用配置的过期时间
+func expirationOf(strategy ExpirationStrategy, key string, expiration time.Duration) time.Duration {
+	if strategy == nil {
+		return expiration
+	}
+	return strategy.Expiration(key, expiration)
+}
+
+// strategyOf 底层的 MapCache 自己配置的 ExpirationStrategy
+func strategyOf(c Cache) ExpirationStrategy {
+	if lc, ok := c.(LocalCache); ok {
+		return lc.mapCache().strategy
+	}
+	return nil
+}
+
+// adjustedSetter 能够写入已经调整过过期时间的数据的包装, 例如 deadlineCache 和 ShardedMapCache
+type adjustedSetter interface {
+	setAdjusted(ctx context.Context, key string, val any, expiration time.Duration) error
+}
+
+// setWithStrategy 装饰器写入底层缓存, 同一次写入只在一个地方调整过期时间:
+// strategy 不为 nil 时由装饰器调整, 底层的 MapCache 不会再用自己的 ExpirationStrategy 调整一次
+func setWithStrategy(ctx context.Context, c Cache, strategy ExpirationStrategy,
+	key string, val any, expiration time.Duration) error {
+	if strategy == nil {
+		return c.Set(ctx, key, val, expiration)
+	}
+	return setAdjusted(ctx, c, key, val, strategy.Expiration(key, expiration))
+}
+
+// setAdjusted expiration 已经调整过, LocalCache 通过 add 跳过自己的 ExpirationStrategy
+func setAdjusted(ctx context.Context, c Cache, key string, val any, expiration time.Duration) error {
+	switch v := c.(type) {
+	case adjustedSetter:
+		return v.setAdjusted(ctx, key, val, expiration)
+	case LocalCache:
+		m := v.mapCache()
+		if m.closing.Load() {
+			return ErrCacheClosed
+		}
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return v.add(key, val, expiration)
+	default:
+		return c.Set(ctx, key, val, expiration)
+	}
+}
+
+// setMultiAdjusted 批量版本的 setAdjusted
+func setMultiAdjusted(ctx context.Context, c BatchCache, entries []Entry, expiration time.Duration) ([]Result, error) {
+	switch v := c.(type) {
+	case LocalCache:
+		m := v.mapCache()
+		if m.closing.Load() {
+			return nil, ErrCacheClosed
+		}
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		res := make([]Result, len(entries))
+		for i, e := range entries {
+			res[i] = Result{Key: e.Key, Err: v.add(e.Key, e.Val, expiration)}
+		}
+		return res, nil
+	case *loopBatchCache:
+		res := make([]Result, len(entries))
+		for i, e := range entries {
+			res[i] = Result{Key: e.Key, Err: setAdjusted(ctx, v.Cache, e.Key, e.Val, expiration)}
+		}
+		return res, nil
+	default:
+		return c.SetMulti(ctx, entries, expiration)
+	}
+}
@@ -0,0 +1,208 @@
+package gcache
+
+import (
+	"context"
+	"fmt"
+	"github.com/NotFound1911/gcache/clock/clocktest"
+	"github.com/NotFound1911/gcache/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestExpirationStrategy(t *testing.T) {
+	testCases := []struct {
+		name       string
+		strategy   ExpirationStrategy
+		expiration time.Duration
+
+		wantMin time.Duration
+		wantMax time.Duration
+	}{
+		{
+			name:       "nil",
+			expiration: time.Minute,
+			wantMin:    time.Minute,
+			wantMax:    time.Minute,
+		},
+		{
+			name:       "fixed",
+			strategy:   FixedExpiration{},
+			expiration: time.Minute,
+			wantMin:    time.Minute,
+			wantMax:    time.Minute,
+		},
+		{
+			name:       "jitter",
+			strategy:   NewJitterExpiration(0.1),
+			expiration: time.Minute,
+			wantMin:    54 * time.Second,
+			wantMax:    66 * time.Second,
+		},
+		{
+			name:     "jitter never expire",
+			strategy: NewJitterExpiration(0.1),
+		},
+		{
+			name: "func",
+			strategy: ExpirationFunc(func(key string, expiration time.Duration) time.Duration {
+				return expiration * 2
+			}),
+			expiration: time.Minute,
+			wantMin:    2 * time.Minute,
+			wantMax:    2 * time.Minute,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				exp := expirationOf(tc.strategy, "key1", tc.expiration)
+				assert.GreaterOrEqual(t, exp, tc.wantMin)
+				assert.LessOrEqual(t, exp, tc.wantMax)
+			}
+		})
+	}
+}
+
+func TestJitterExpiration_Spread(t *testing.T) {
+	j := NewJitterExpiration(0.2)
+	seen := make(map[time.Duration]struct{})
+	for i := 0; i < 100; i++ {
+		seen[j.Expiration("key1", time.Hour)] = struct{}{}
+	}
+	assert.Greater(t, len(seen), 50)
+}
+
+func TestMapCache_ExpirationStrategy(t *testing.T) {
+	clk := clocktest.NewFakeClock(time.Now())
+	cache := NewMapCache(time.Minute, BuildMapCacheWithClock(clk),
+		BuildMapCacheWithExpirationStrategy(ExpirationFunc(func(key string, expiration time.Duration) time.Duration {
+			if key == "short" {
+				return expiration / 2
+			}
+			return expiration
+		})))
+	defer cache.Close()
+	ctx := context.Background()
+	require.NoError(t, cache.Set(ctx, "short", 1, 10*time.Second))
+	require.NoError(t, cache.Set(ctx, "long", 1, 10*time.Second))
+	clk.Advance(6 * time.Second)
+	_, err := cache.Get(ctx, "short")
+	assert.Equal(t, fmt.Errorf("%w, key: %s", ErrKeyNotFound, "short"), err)
+	_, err = cache.Get(ctx, "long")
+	assert.NoError(t, err)
+}
+
+func TestReadTroughCache_ExpirationStrategy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := mocks.NewMockCmdable(ctrl)
+	miss := redis.NewStringCmd(context.Background())
+	miss.SetErr(redis.Nil)
+	cmd.EXPECT().Get(gomock.Any(), "key1").Return(miss)
+	status := redis.NewStatusCmd(context.Background())
+	status.SetVal("OK")
+	cmd.EXPECT().Set(gomock.Any(), "key1", "loaded", 90*time.Second).Return(status)
+	c := &ReadTroughCache{
+		Cache: NewRedisCache(cmd),
+		LoadFunc: func(ctx context.Context, key string) (any, error) {
+			return "loaded", nil
+		},
+		Expiration: time.Minute,
+		ExpirationStrategy: ExpirationFunc(func(key string, expiration time.Duration) time.Duration {
+			return expiration + 30*time.Second
+		}),
+	}
+	val, err := c.Get(context.Background(), "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "loaded", val)
+}
+
+func TestExpirationStrategy_AppliedOnce(t *testing.T) {
+	clk := clocktest.NewFakeClock(time.Now())
+	half := ExpirationFunc(func(key string, expiration time.Duration) time.Duration {
+		return expiration / 2
+	})
+	local := NewMapCache(time.Minute, BuildMapCacheWithClock(clk), BuildMapCacheWithExpirationStrategy(half))
+	defer local.Close()
+	ctx := context.Background()
+	loadFunc := func(ctx context.Context, key string) (any, error) {
+		return "val", nil
+	}
+
+	// 上层已经调整过, MapCache 不再调整
+	c := &ReadTroughCache{
+		Cache:      local,
+		LoadFunc:   loadFunc,
+		Expiration: time.Minute,
+		ExpirationStrategy: ExpirationFunc(func(key string, expiration time.Duration) time.Duration {
+			return expiration + 30*time.Second
+		}),
+	}
+	_, err := c.Get(ctx, "key1")
+	require.NoError(t, err)
+	_, ttl, err := local.GetWithTTL(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, ttl)
+
+	// 上层没有配置的时候使用 MapCache 的
+	w := &WriteThroughCache{
+		Cache: local,
+		StoreFunc: func(ctx context.Context, key string, val any) error {
+			return nil
+		},
+	}
+	require.NoError(t, w.Set(ctx, "key2", "val", time.Minute))
+	_, ttl, err = local.GetWithTTL(ctx, "key2")
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, ttl)
+
+	// RefreshAhead 记录的过期时间和 MapCache 中的一致
+	r := NewRefreshAheadCache(local, loadFunc, time.Minute, BuildRefreshAheadWithClock(clk))
+	_, err = r.Get(ctx, "key3")
+	require.NoError(t, err)
+	_, ttl, err = local.GetWithTTL(ctx, "key3")
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, ttl)
+	r.mu.Lock()
+	assert.Equal(t, clk.Now().Add(ttl), r.entries["key3"].deadline)
+	r.mu.Unlock()
+}
+
+func TestBatchReadThroughCache_ExpirationStrategy(t *testing.T) {
+	clk := clocktest.NewFakeClock(time.Now())
+	// MapCache 自己的 strategy 不会在批次调整过的过期时间上再调整一次
+	local := NewMapCache(time.Minute, BuildMapCacheWithClock(clk),
+		BuildMapCacheWithExpirationStrategy(ExpirationFunc(func(key string, expiration time.Duration) time.Duration {
+			return expiration / 2
+		})))
+	defer local.Close()
+	var keys []string
+	c := NewBatchReadThroughCache(local, func(ctx context.Context, keys []string) (map[string]any, error) {
+		res := make(map[string]any, len(keys))
+		for _, key := range keys {
+			res[key] = "val_" + key
+		}
+		return res, nil
+	}, time.Minute, BuildBatchReadThroughWithMaxBatch(3),
+		BuildBatchReadThroughWithExpirationStrategy(ExpirationFunc(func(key string, expiration time.Duration) time.Duration {
+			keys = append(keys, key)
+			return expiration + 10*time.Second
+		})))
+	ctx := context.Background()
+	res, err := c.GetMulti(ctx, []string{"key1", "key2", "key3"})
+	require.NoError(t, err)
+	for _, r := range res {
+		require.NoError(t, r.Err)
+	}
+	// 整个批次只调整一次
+	assert.Len(t, keys, 1)
+	for _, key := range []string{"key1", "key2", "key3"} {
+		_, ttl, err := local.GetWithTTL(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, 70*time.Second, ttl, key)
+	}
+}
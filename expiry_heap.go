@@ -0,0 +1,47 @@
+package gcache
+
+import "container/heap"
+
+// expiryHeap 按照过期时间排列的最小堆, 堆顶是最早过期的 item
+// 没有设置过期时间的 item 不会进入堆
+type expiryHeap []*item
+
+var _ heap.Interface = (*expiryHeap)(nil)
+
+func (h expiryHeap) Len() int {
+	return len(h)
+}
+
+func (h expiryHeap) Less(i, j int) bool {
+	return h[i].deadline.Before(h[j].deadline)
+}
+
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap) Push(x any) {
+	itm := x.(*item)
+	itm.index = len(*h)
+	*h = append(*h, itm)
+}
+
+func (h *expiryHeap) Pop() any {
+	old := *h
+	n := len(old)
+	itm := old[n-1]
+	old[n-1] = nil
+	itm.index = -1
+	*h = old[:n-1]
+	return itm
+}
+
+// peek 返回最早过期的 item
+func (h expiryHeap) peek() *item {
+	if len(h) == 0 {
+		return nil
+	}
+	return h[0]
+}
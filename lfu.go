@@ -0,0 +1,114 @@
+package gcache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lfuPolicy 最不经常使用淘汰, 同频率的 key 之间按照 LRU 淘汰
+// 频率桶按照升序串成链表, 所有操作都是 O(1)
+type lfuPolicy struct {
+	mu      sync.Mutex
+	buckets *list.List // 元素是 *lfuBucket
+	entries map[string]*lfuEntry
+}
+
+type lfuBucket struct {
+	freq int
+	keys *list.List // 元素是 key, 头部是最近访问的
+}
+
+type lfuEntry struct {
+	bucket *list.Element
+	elem   *list.Element
+}
+
+func newLFUPolicy() *lfuPolicy {
+	return &lfuPolicy{
+		buckets: list.New(),
+		entries: make(map[string]*lfuEntry, 128),
+	}
+}
+
+func (l *lfuPolicy) OnSet(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if entry, ok := l.entries[key]; ok {
+		l.increment(entry)
+		return
+	}
+	front := l.buckets.Front()
+	if front == nil || front.Value.(*lfuBucket).freq != 1 {
+		front = l.buckets.PushFront(&lfuBucket{freq: 1, keys: list.New()})
+	}
+	l.entries[key] = &lfuEntry{
+		bucket: front,
+		elem:   front.Value.(*lfuBucket).keys.PushFront(key),
+	}
+}
+
+func (l *lfuPolicy) OnGet(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if entry, ok := l.entries[key]; ok {
+		l.increment(entry)
+	}
+}
+
+func (l *lfuPolicy) OnDelete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.entries[key]
+	if !ok {
+		return
+	}
+	l.remove(entry)
+	delete(l.entries, key)
+}
+
+func (l *lfuPolicy) Victim() (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	front := l.buckets.Front()
+	if front == nil {
+		return "", false
+	}
+	return front.Value.(*lfuBucket).keys.Back().Value.(string), true
+}
+
+// increment 把 key 挪到下一个频率桶
+func (l *lfuPolicy) increment(entry *lfuEntry) {
+	cur := entry.bucket
+	freq := cur.Value.(*lfuBucket).freq + 1
+	next := cur.Next()
+	if next == nil || next.Value.(*lfuBucket).freq != freq {
+		next = l.buckets.InsertAfter(&lfuBucket{freq: freq, keys: list.New()}, cur)
+	}
+	key := entry.elem.Value.(string)
+	l.remove(entry)
+	entry.bucket = next
+	entry.elem = next.Value.(*lfuBucket).keys.PushFront(key)
+}
+
+// remove 把 key 从当前的频率桶中移除, 空桶会被一起删除
+func (l *lfuPolicy) remove(entry *lfuEntry) {
+	bucket := entry.bucket.Value.(*lfuBucket)
+	bucket.keys.Remove(entry.elem)
+	if bucket.keys.Len() == 0 {
+		l.buckets.Remove(entry.bucket)
+	}
+}
+
+// NewLFUPolicy 创建 LFU 淘汰策略
+func NewLFUPolicy() EvictionPolicy {
+	return newLFUPolicy()
+}
+
+// BuildMapCacheWithLFU 使用 LFU 淘汰策略
+// 配合 MaxCntCache 使用时, 超过容量会淘汰访问次数最少的 key
+func BuildMapCacheWithLFU() MapCacheOption {
+	return func(cache *MapCache) {
+		cache.policy = newLFUPolicy()
+		cache.sharedPolicy = false
+	}
+}
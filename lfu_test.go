@@ -0,0 +1,114 @@
+package gcache
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestMaxCntCache_LFU(t *testing.T) {
+	var evicted []string
+	c := NewMaxCntCache(NewMapCache(time.Minute, BuildMapCacheWithLFU(),
+		BuildMapCacheWithEvictedCallback(func(key string, val any) {
+			evicted = append(evicted, key)
+		})), 3)
+	defer c.Close()
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, c.Set(ctx, fmt.Sprintf("key_%d", i), i, time.Minute))
+	}
+	for i := 0; i < 2; i++ {
+		_, err := c.Get(ctx, "key_0")
+		require.NoError(t, err)
+	}
+	_, err := c.Get(ctx, "key_2")
+	require.NoError(t, err)
+
+	// key_1 访问次数最少
+	require.NoError(t, c.Set(ctx, "key_3", 3, time.Minute))
+	assert.Equal(t, []string{"key_1"}, evicted)
+
+	// key_3 和 key_2 相比, key_3 访问次数更少
+	require.NoError(t, c.Set(ctx, "key_4", 4, time.Minute))
+	assert.Equal(t, []string{"key_1", "key_3"}, evicted)
+	assert.Equal(t, int32(3), c.cnt)
+}
+
+func TestLFUPolicy_SameFreq(t *testing.T) {
+	p := newLFUPolicy()
+	p.OnSet("key1")
+	p.OnSet("key2")
+	p.OnSet("key3")
+	// 同频率按照 LRU 淘汰
+	victim, ok := p.Victim()
+	require.True(t, ok)
+	assert.Equal(t, "key1", victim)
+
+	p.OnGet("key1")
+	p.OnDelete("key2")
+	victim, ok = p.Victim()
+	require.True(t, ok)
+	assert.Equal(t, "key3", victim)
+
+	p.OnDelete("key3")
+	p.OnDelete("key1")
+	_, ok = p.Victim()
+	assert.False(t, ok)
+	assert.Equal(t, 0, p.buckets.Len())
+}
+
+func TestCMSketch(t *testing.T) {
+	s := newCMSketch(16)
+	for i := 0; i < 5; i++ {
+		s.Increment("hot")
+	}
+	s.Increment("cold")
+	assert.Equal(t, uint8(5), s.Estimate("hot"))
+	assert.Equal(t, uint8(1), s.Estimate("cold"))
+	for i := 0; i < 100; i++ {
+		s.Increment("hot")
+	}
+	assert.Equal(t, uint8(cmMaxFreq), s.Estimate("hot"))
+	s.reset()
+	assert.Equal(t, uint8(cmMaxFreq/2), s.Estimate("hot"))
+}
+
+func TestMaxCntCache_WTinyLFU(t *testing.T) {
+	const capacity = 100
+	evicted := 0
+	c := NewMaxCntCache(NewMapCache(time.Minute, BuildMapCacheWithWTinyLFU(capacity),
+		BuildMapCacheWithEvictedCallback(func(key string, val any) {
+			evicted++
+		})), capacity)
+	defer c.Close()
+	ctx := context.Background()
+	hot := make([]string, 0, 50)
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("hot_%d", i)
+		hot = append(hot, key)
+		require.NoError(t, c.Set(ctx, key, i, time.Minute))
+	}
+	for j := 0; j < 5; j++ {
+		for _, key := range hot {
+			_, err := c.Get(ctx, key)
+			require.NoError(t, err)
+		}
+	}
+	// 一次性扫描大量冷数据
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, c.Set(ctx, fmt.Sprintf("scan_%d", i), i, time.Minute))
+	}
+	// 最后写入的热点 key 还停留在窗口区, 和主区的热点频率相同, 可能会被淘汰
+	hits := 0
+	for _, key := range hot {
+		if _, err := c.Get(ctx, key); err == nil {
+			hits++
+		}
+	}
+	assert.GreaterOrEqual(t, hits, len(hot)-1)
+	assert.Equal(t, int32(capacity), c.cnt)
+	assert.Equal(t, 950, evicted)
+}
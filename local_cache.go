@@ -1,30 +1,54 @@
 package gcache
 
 import (
+	"container/heap"
 	"context"
-	"errors"
 	"fmt"
+	"github.com/NotFound1911/gcache/clock"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+type MapCacheOption func(cache *MapCache)
+
+// LocalCache MapCache 以及带有容量限制的 MaxCntCache 和 MaxSizeCache
+// 需要在 MapCache 之上叠加写入逻辑的装饰器, 例如 WriteBackCache, 通过它保留容量限制
+type LocalCache interface {
+	Cache
+	mapCache() *MapCache
+	add(key string, val any, expiration time.Duration) error
+}
+
 var (
-	errKeyNotFound = errors.New("gcache 键不存在")
+	_ TTLCache   = (*MapCache)(nil)
+	_ LocalCache = (*MapCache)(nil)
+	_ LocalCache = (*MaxCntCache)(nil)
+	_ LocalCache = (*MaxSizeCache)(nil)
 )
 
-type MapCacheOption func(cache *MapCache)
-
 type MapCache struct {
 	data      map[string]*item
+	expiries  expiryHeap // 过期时间索引
 	mu        sync.RWMutex
-	onEvicted func(key string, val any)
+	onEvicted func(key string, val any, reason EvictionReason)
+	policy    EvictionPolicy
+	clock     clock.Clock
+	strategy  ExpirationStrategy
 	close     chan struct{}
+	closing   atomic.Bool
+	stats     statsCounter
 	maxCnt    int
 	closed    bool
+
+	// sharedPolicy 淘汰策略是调用方直接传入的实例, 可能被多个 MapCache 共享
+	sharedPolicy bool
 }
 type item struct {
+	key      string
 	val      any
 	deadline time.Time // 过期时间
+	index    int       // 在 expiryHeap 中的下标, -1 表示不在堆中
 }
 
 // deadlineBefore 是否在时间t前面 用于判断过期
@@ -36,33 +60,38 @@ func NewMapCache(interval time.Duration, opts ...MapCacheOption) *MapCache {
 	cache := &MapCache{
 		data:  make(map[string]*item, 128),
 		close: make(chan struct{}),
-		onEvicted: func(key string, val any) {
+		onEvicted: func(key string, val any, reason EvictionReason) {
 		},
 		maxCnt: 10000,
+		policy: nopPolicy{},
+		clock:  clock.New(),
 	}
 	for _, opt := range opts {
 		opt(cache)
 	}
+	ticker := cache.clock.NewTicker(interval)
 	go func() {
-		ticker := time.NewTicker(interval)
 		for {
 			select {
-			case t := <-ticker.C:
+			case t := <-ticker.C():
 				cache.mu.Lock()
-				i := 0
-				for key, val := range cache.data {
-					// 随机过期
-					if i >= cache.maxCnt {
+				// 从堆顶开始清理, 每次最多清理 maxCnt 个
+				for i := 0; i < cache.maxCnt; i++ {
+					itm := cache.expiries.peek()
+					if itm == nil || !itm.deadlineBefore(t) {
 						break
 					}
-					if val.deadlineBefore(t) {
-						cache.delete(key)
-					}
-					i++
+					cache.delete(itm.key, EvictionExpired)
 				}
 				cache.mu.Unlock()
 			case <-cache.close:
+				ticker.Stop()
+				cache.mu.Lock()
+				for key := range cache.data {
+					cache.delete(key, EvictionClosed)
+				}
 				cache.closed = true
+				cache.mu.Unlock()
 				return
 			}
 		}
@@ -71,79 +100,252 @@ func NewMapCache(interval time.Duration, opts ...MapCacheOption) *MapCache {
 }
 
 func (m *MapCache) Set(ctx context.Context, key string, val any, expiration time.Duration) error {
+	if m.closing.Load() {
+		return ErrCacheClosed
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	return m.set(key, val, expiration)
+	return m.set(key, val, expirationOf(m.strategy, key, expiration))
 }
+
 func (m *MapCache) set(key string, val any, expiration time.Duration) error {
 	var dl time.Time
 	if expiration > 0 {
-		dl = time.Now().Add(expiration)
+		dl = m.clock.Now().Add(expiration)
+	}
+	m.stats.sets.Add(1)
+	old, replaced := m.data[key]
+	if replaced && old.index >= 0 {
+		heap.Remove(&m.expiries, old.index)
 	}
-	m.data[key] = &item{
+	itm := &item{
+		key:      key,
 		val:      val,
 		deadline: dl,
+		index:    -1,
+	}
+	m.data[key] = itm
+	if !dl.IsZero() {
+		heap.Push(&m.expiries, itm)
+	}
+	m.policy.OnSet(key)
+	if replaced {
+		m.stats.evicted(EvictionReplaced)
+		m.onEvicted(key, old.val, EvictionReplaced)
 	}
 	return nil
 }
+
+// add 调用方需要持有写锁, MaxCntCache 和 MaxSizeCache 会在这里加上容量限制
+// expiration 是已经调整过的过期时间, 不会再经过 ExpirationStrategy
+func (m *MapCache) add(key string, val any, expiration time.Duration) error {
+	return m.set(key, val, expiration)
+}
+
+func (m *MapCache) mapCache() *MapCache {
+	return m
+}
+
 func (m *MapCache) Get(ctx context.Context, key string) (any, error) {
+	itm, err := m.get(key, m.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	return itm.val, nil
+}
+
+// GetWithTTL 剩余过期时间根据写入时计算出来的过期时间得到, 已经包含了 ExpirationStrategy 的调整
+func (m *MapCache) GetWithTTL(ctx context.Context, key string) (any, time.Duration, error) {
+	now := m.clock.Now()
+	itm, err := m.get(key, now)
+	if err != nil {
+		return nil, 0, err
+	}
+	if itm.deadline.IsZero() {
+		return itm.val, NoExpiration, nil
+	}
+	return itm.val, itm.deadline.Sub(now), nil
+}
+
+func (m *MapCache) get(key string, now time.Time) (*item, error) {
+	if m.closing.Load() {
+		return nil, ErrCacheClosed
+	}
 	m.mu.RLock()
 	res, ok := m.data[key]
+	if ok && !res.deadlineBefore(now) {
+		m.policy.OnGet(key)
+	}
 	m.mu.RUnlock()
 	if !ok {
-		return nil, fmt.Errorf("%w, key: %s", errKeyNotFound, key)
+		m.stats.misses.Add(1)
+		return nil, fmt.Errorf("%w, key: %s", ErrKeyNotFound, key)
 	}
-	now := time.Now()
 	if res.deadlineBefore(now) { // 过期清理
 		m.mu.Lock()
 		defer m.mu.Unlock()
-		res, ok := m.data[key]
+		res, ok = m.data[key]
 		if !ok { // 已经被清理
-			return nil, fmt.Errorf("%w, key: %s", errKeyNotFound, key)
+			m.stats.misses.Add(1)
+			return nil, fmt.Errorf("%w, key: %s", ErrKeyNotFound, key)
 		}
 		// 二次确定过期
 		if res.deadlineBefore(now) {
-			m.delete(key)
-			return nil, fmt.Errorf("%w, key: %s", errKeyNotFound, key)
+			m.delete(key, EvictionExpired)
+			m.stats.misses.Add(1)
+			return nil, fmt.Errorf("%w, key: %s", ErrKeyNotFound, key)
 		}
 	}
-	return res.val, nil
+	m.stats.hits.Add(1)
+	return res, nil
 }
-func (m *MapCache) delete(key string) {
+func (m *MapCache) delete(key string, reason EvictionReason) {
 	itm, ok := m.data[key]
 	if !ok {
 		return
 	}
 	delete(m.data, key)
-	m.onEvicted(key, itm.val)
+	if itm.index >= 0 {
+		heap.Remove(&m.expiries, itm.index)
+	}
+	m.policy.OnDelete(key)
+	m.stats.evicted(reason)
+	m.onEvicted(key, itm.val, reason)
 }
 func (m *MapCache) Delete(ctx context.Context, key string) error {
+	if m.closing.Load() {
+		return ErrCacheClosed
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.delete(key)
+	m.stats.deletes.Add(1)
+	m.delete(key, EvictionDeleted)
 	return nil
 }
 
 func (m *MapCache) LoadAndDelete(ctx context.Context, key string) (any, error) {
+	if m.closing.Load() {
+		return nil, ErrCacheClosed
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.stats.deletes.Add(1)
 	val, ok := m.data[key]
 	if !ok {
-		return nil, fmt.Errorf("%w, key:%s", errKeyNotFound, key)
+		return nil, fmt.Errorf("%w, key: %s", ErrKeyNotFound, key)
 	}
-	m.delete(key)
+	m.delete(key, EvictionDeleted)
 	return val.val, nil
 }
+
+// GetMulti 只加一次锁, 顺便清理已经过期的 key
+func (m *MapCache) GetMulti(ctx context.Context, keys []string) ([]Result, error) {
+	if m.closing.Load() {
+		return nil, ErrCacheClosed
+	}
+	now := m.clock.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := make([]Result, len(keys))
+	for i, key := range keys {
+		res[i].Key = key
+		itm, ok := m.data[key]
+		if ok && itm.deadlineBefore(now) {
+			m.delete(key, EvictionExpired)
+			ok = false
+		}
+		if !ok {
+			m.stats.misses.Add(1)
+			res[i].Err = fmt.Errorf("%w, key: %s", ErrKeyNotFound, key)
+			continue
+		}
+		m.stats.hits.Add(1)
+		m.policy.OnGet(key)
+		res[i].Val = itm.val
+	}
+	return res, nil
+}
+
+func (m *MapCache) SetMulti(ctx context.Context, entries []Entry, expiration time.Duration) ([]Result, error) {
+	if m.closing.Load() {
+		return nil, ErrCacheClosed
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := make([]Result, len(entries))
+	for i, e := range entries {
+		res[i] = Result{Key: e.Key, Err: m.set(e.Key, e.Val, expirationOf(m.strategy, e.Key, expiration))}
+	}
+	return res, nil
+}
+
+func (m *MapCache) DeleteMulti(ctx context.Context, keys []string) ([]Result, error) {
+	if m.closing.Load() {
+		return nil, ErrCacheClosed
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := make([]Result, len(keys))
+	for i, key := range keys {
+		m.stats.deletes.Add(1)
+		m.delete(key, EvictionDeleted)
+		res[i] = Result{Key: key}
+	}
+	return res, nil
+}
+
+func (m *MapCache) Stats() Stats {
+	res := m.stats.snapshot()
+	m.mu.RLock()
+	res.Entries = int64(len(m.data))
+	m.mu.RUnlock()
+	return res
+}
+
+func (m *MapCache) ResetStats() {
+	m.stats.reset()
+}
+
 func (m *MapCache) Close() error {
-	select {
-	case m.close <- struct{}{}:
-	default:
-		return errors.New("gcache 重复关闭")
+	// 清理协程可能正忙, 不能依赖它及时接收信号来判断是否重复关闭
+	if !m.closing.CompareAndSwap(false, true) {
+		return fmt.Errorf("%w, 重复关闭", ErrCacheClosed)
 	}
+	close(m.close)
 	return nil
 }
+
+// BuildMapCacheWithEvictedCallback 只会在删除, 过期和容量淘汰的时候回调
+// 需要区分原因或者关心覆盖和关闭的, 使用 BuildMapCacheWithEvictedReasonCallback
 func BuildMapCacheWithEvictedCallback(fn func(key string, val any)) MapCacheOption {
+	return func(cache *MapCache) {
+		cache.onEvicted = func(key string, val any, reason EvictionReason) {
+			switch reason {
+			case EvictionDeleted, EvictionExpired, EvictionCapacity:
+				fn(key, val)
+			}
+		}
+	}
+}
+
+// BuildMapCacheWithEvictedReasonCallback 数据被移出缓存时回调, reason 说明了移出的原因
+func BuildMapCacheWithEvictedReasonCallback(fn func(key string, val any, reason EvictionReason)) MapCacheOption {
 	return func(cache *MapCache) {
 		cache.onEvicted = fn
 	}
 }
+
+// BuildMapCacheWithClock 使用自定义的时钟, 测试中可以用 clocktest.FakeClock 控制过期
+func BuildMapCacheWithClock(c clock.Clock) MapCacheOption {
+	return func(cache *MapCache) {
+		cache.clock = c
+	}
+}
+
+// BuildMapCacheWithExpirationStrategy 所有写入的过期时间都会经过 strategy 调整
+// 上层的装饰器配置了自己的 ExpirationStrategy 时, 它的写入不会再次调整
+func BuildMapCacheWithExpirationStrategy(s ExpirationStrategy) MapCacheOption {
+	return func(cache *MapCache) {
+		cache.strategy = s
+	}
+}
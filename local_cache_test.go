@@ -3,6 +3,7 @@ package gcache
 import (
 	"context"
 	"fmt"
+	"github.com/NotFound1911/gcache/clock/clocktest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -23,7 +24,7 @@ func TestMapCache_Get(t *testing.T) {
 			cache: func() *MapCache {
 				return NewMapCache(10 * time.Second)
 			},
-			wantErr: fmt.Errorf("%w, key: %s", errKeyNotFound, "invalid key"),
+			wantErr: fmt.Errorf("%w, key: %s", ErrKeyNotFound, "invalid key"),
 		},
 		{
 			name: "get value",
@@ -40,13 +41,14 @@ func TestMapCache_Get(t *testing.T) {
 			name: "time expired",
 			key:  "expired key",
 			cache: func() *MapCache {
-				res := NewMapCache(10 * time.Second)
+				clk := clocktest.NewFakeClock(time.Now())
+				res := NewMapCache(10*time.Second, BuildMapCacheWithClock(clk))
 				err := res.Set(context.Background(), "expired key", 456, time.Second)
 				require.NoError(t, err)
-				time.Sleep(time.Second * 2)
+				clk.Advance(time.Second * 2)
 				return res
 			},
-			wantErr: fmt.Errorf("%w, key: %s", errKeyNotFound, "expired key"),
+			wantErr: fmt.Errorf("%w, key: %s", ErrKeyNotFound, "expired key"),
 		},
 	}
 	for _, tc := range testCases {
@@ -61,19 +63,52 @@ func TestMapCache_Get(t *testing.T) {
 	}
 }
 
+func TestMapCache_GetWithTTL(t *testing.T) {
+	clk := clocktest.NewFakeClock(time.Now())
+	c := NewMapCache(10*time.Second, BuildMapCacheWithClock(clk))
+	defer c.Close()
+	ctx := context.Background()
+	require.NoError(t, c.Set(ctx, "key1", 123, time.Minute))
+	require.NoError(t, c.Set(ctx, "key2", "val2", 0))
+	clk.Advance(time.Second * 10)
+
+	val, ttl, err := c.GetWithTTL(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, 123, val)
+	assert.Equal(t, time.Second*50, ttl)
+	val, ttl, err = c.GetWithTTL(ctx, "key2")
+	require.NoError(t, err)
+	assert.Equal(t, "val2", val)
+	assert.Equal(t, NoExpiration, ttl)
+
+	clk.Advance(time.Minute)
+	_, _, err = c.GetWithTTL(ctx, "key1")
+	assert.Equal(t, fmt.Errorf("%w, key: %s", ErrKeyNotFound, "key1"), err)
+}
+
+// eventually 等待清理协程处理完, 在持有锁的情况下检查条件
+func eventually(t *testing.T, cache *MapCache, condition func() bool) {
+	require.Eventually(t, func() bool {
+		cache.mu.RLock()
+		defer cache.mu.RUnlock()
+		return condition()
+	}, time.Second, time.Millisecond)
+}
+
 func TestMapCache_Loop(t *testing.T) {
 	cnt := 0
-	cache := NewMapCache(time.Second, BuildMapCacheWithEvictedCallback(func(key string, val any) {
-		cnt++
-	}))
+	clk := clocktest.NewFakeClock(time.Now())
+	cache := NewMapCache(time.Second, BuildMapCacheWithClock(clk),
+		BuildMapCacheWithEvictedCallback(func(key string, val any) {
+			cnt++
+		}))
 	err := cache.Set(context.Background(), "key", 456, time.Second)
 	require.NoError(t, err)
-	time.Sleep(time.Second * 3)
-	cache.mu.RLock()
-	defer cache.mu.RUnlock()
-	_, ok := cache.data["key"]
-	require.False(t, ok)
-	require.Equal(t, 1, cnt)
+	clk.Advance(time.Second * 2)
+	eventually(t, cache, func() bool {
+		_, ok := cache.data["key"]
+		return !ok && cnt == 1
+	})
 }
 
 func TestMapCache_Ticker(t *testing.T) {
@@ -81,7 +116,8 @@ func TestMapCache_Ticker(t *testing.T) {
 	setMacCnt := func(cache *MapCache) {
 		cache.maxCnt = 3
 	}
-	cache := NewMapCache(2*time.Second, setMacCnt,
+	clk := clocktest.NewFakeClock(time.Now())
+	cache := NewMapCache(2*time.Second, setMacCnt, BuildMapCacheWithClock(clk),
 		BuildMapCacheWithEvictedCallback(func(key string, val any) {
 			cnt++
 		}))
@@ -90,7 +126,10 @@ func TestMapCache_Ticker(t *testing.T) {
 		err := cache.Set(context.Background(), key, struct{}{}, 1*time.Second)
 		require.NoError(t, err)
 	}
-	time.Sleep(time.Second * 3)
+	clk.Advance(time.Second * 2)
+	eventually(t, cache, func() bool {
+		return cnt == cache.maxCnt
+	})
 	cache.mu.RLock()
 	defer cache.mu.RUnlock()
 	cntFalse := 0
@@ -108,9 +147,113 @@ func TestMapCache_Ticker(t *testing.T) {
 func TestMapCache_Close(t *testing.T) {
 	cache := NewMapCache(time.Second)
 	require.False(t, cache.closed)
-	time.Sleep(time.Second)
 	err := cache.Close()
-	time.Sleep(2*time.Second)
 	require.NoError(t, err)
-	require.True(t, cache.closed)
+	eventually(t, cache, func() bool {
+		return cache.closed
+	})
+	err = cache.Close()
+	assert.ErrorIs(t, err, ErrCacheClosed)
+	err = cache.Set(context.Background(), "key", 1, time.Minute)
+	assert.Equal(t, ErrCacheClosed, err)
+	_, err = cache.Get(context.Background(), "key")
+	assert.Equal(t, ErrCacheClosed, err)
+}
+
+func TestMapCache_ExpiryHeap(t *testing.T) {
+	var evicted []string
+	clk := clocktest.NewFakeClock(time.Now())
+	cache := NewMapCache(time.Second, BuildMapCacheWithClock(clk),
+		BuildMapCacheWithEvictedCallback(func(key string, val any) {
+			evicted = append(evicted, key)
+		}))
+	ctx := context.Background()
+	require.NoError(t, cache.Set(ctx, "key3", 3, 3*time.Second))
+	require.NoError(t, cache.Set(ctx, "key1", 1, time.Second))
+	require.NoError(t, cache.Set(ctx, "key2", 2, 2*time.Second))
+	require.NoError(t, cache.Set(ctx, "forever", 0, 0))
+	require.NoError(t, cache.Set(ctx, "long", 0, time.Minute))
+	// 覆盖之后使用新的过期时间
+	require.NoError(t, cache.Set(ctx, "key2", 2, time.Minute))
+	require.NoError(t, cache.Delete(ctx, "key3"))
+
+	cache.mu.RLock()
+	require.Equal(t, 3, cache.expiries.Len())
+	assert.Equal(t, "key1", cache.expiries.peek().key)
+	cache.mu.RUnlock()
+
+	clk.Advance(5 * time.Second)
+	eventually(t, cache, func() bool {
+		return len(evicted) == 2
+	})
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	assert.Equal(t, []string{"key3", "key1"}, evicted)
+	assert.Equal(t, 2, cache.expiries.Len())
+	assert.Equal(t, 3, len(cache.data))
+	for i, itm := range cache.expiries {
+		assert.Equal(t, i, itm.index)
+	}
+}
+
+func TestMapCache_EvictionReason(t *testing.T) {
+	type evicted struct {
+		key    string
+		reason EvictionReason
+	}
+	var reasons []evicted
+	var legacy []string
+	clk := clocktest.NewFakeClock(time.Now())
+	cache := NewMaxCntCache(NewMapCache(time.Second, BuildMapCacheWithLRU(), BuildMapCacheWithClock(clk),
+		BuildMapCacheWithEvictedReasonCallback(func(key string, val any, reason EvictionReason) {
+			reasons = append(reasons, evicted{key: key, reason: reason})
+		})), 2)
+	ctx := context.Background()
+	require.NoError(t, cache.Set(ctx, "deleted", 1, time.Minute))
+	require.NoError(t, cache.Delete(ctx, "deleted"))
+	require.NoError(t, cache.Set(ctx, "loaded", 1, time.Minute))
+	_, err := cache.LoadAndDelete(ctx, "loaded")
+	require.NoError(t, err)
+	require.NoError(t, cache.Set(ctx, "replaced", 1, time.Minute))
+	require.NoError(t, cache.Set(ctx, "replaced", 2, time.Minute))
+	require.NoError(t, cache.Set(ctx, "expired", 1, time.Millisecond))
+	clk.Advance(time.Second)
+	eventually(t, cache.MapCache, func() bool {
+		return len(reasons) == 4
+	})
+	require.NoError(t, cache.Set(ctx, "closed", 1, time.Minute))
+	require.NoError(t, cache.Set(ctx, "capacity", 1, time.Minute))
+	require.NoError(t, cache.Close())
+	eventually(t, cache.MapCache, func() bool {
+		return cache.closed
+	})
+
+	require.Len(t, reasons, 7)
+	assert.Equal(t, []evicted{
+		{key: "deleted", reason: EvictionDeleted},
+		{key: "loaded", reason: EvictionDeleted},
+		{key: "replaced", reason: EvictionReplaced},
+		{key: "expired", reason: EvictionExpired},
+		{key: "replaced", reason: EvictionCapacity},
+	}, reasons[:5])
+	// 关闭时按照 map 的顺序清理
+	assert.ElementsMatch(t, []evicted{
+		{key: "closed", reason: EvictionClosed},
+		{key: "capacity", reason: EvictionClosed},
+	}, reasons[5:])
+	assert.Equal(t, int32(0), cache.cnt)
+
+	// 老的回调不关心覆盖和关闭
+	old := NewMapCache(time.Minute, BuildMapCacheWithEvictedCallback(func(key string, val any) {
+		legacy = append(legacy, key)
+	}))
+	require.NoError(t, old.Set(ctx, "key", 1, time.Minute))
+	require.NoError(t, old.Set(ctx, "key", 2, time.Minute))
+	require.NoError(t, old.Set(ctx, "other", 1, time.Minute))
+	require.NoError(t, old.Delete(ctx, "key"))
+	require.NoError(t, old.Close())
+	eventually(t, old, func() bool {
+		return old.closed
+	})
+	assert.Equal(t, []string{"key"}, legacy)
 }
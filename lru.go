@@ -0,0 +1,71 @@
+package gcache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruPolicy 最近最少使用淘汰, 链表头部是最近访问的 key
+type lruPolicy struct {
+	mu    sync.Mutex
+	list  *list.List
+	elems map[string]*list.Element
+}
+
+func newLRUPolicy() *lruPolicy {
+	return &lruPolicy{
+		list:  list.New(),
+		elems: make(map[string]*list.Element, 128),
+	}
+}
+
+func (l *lruPolicy) OnSet(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if elem, ok := l.elems[key]; ok {
+		l.list.MoveToFront(elem)
+		return
+	}
+	l.elems[key] = l.list.PushFront(key)
+}
+
+func (l *lruPolicy) OnGet(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if elem, ok := l.elems[key]; ok {
+		l.list.MoveToFront(elem)
+	}
+}
+
+func (l *lruPolicy) OnDelete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if elem, ok := l.elems[key]; ok {
+		l.list.Remove(elem)
+		delete(l.elems, key)
+	}
+}
+
+func (l *lruPolicy) Victim() (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	elem := l.list.Back()
+	if elem == nil {
+		return "", false
+	}
+	return elem.Value.(string), true
+}
+
+// NewLRUPolicy 创建 LRU 淘汰策略
+func NewLRUPolicy() EvictionPolicy {
+	return newLRUPolicy()
+}
+
+// BuildMapCacheWithLRU 使用 LRU 淘汰策略
+// 配合 MaxCntCache 使用时, 超过容量会淘汰最久没有访问的 key 而不是拒绝写入
+func BuildMapCacheWithLRU() MapCacheOption {
+	return func(cache *MapCache) {
+		cache.policy = newLRUPolicy()
+		cache.sharedPolicy = false
+	}
+}
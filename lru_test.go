@@ -0,0 +1,63 @@
+package gcache
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestMaxCntCache_LRU(t *testing.T) {
+	var evicted []string
+	c := NewMaxCntCache(NewMapCache(time.Minute, BuildMapCacheWithLRU(),
+		BuildMapCacheWithEvictedCallback(func(key string, val any) {
+			evicted = append(evicted, key)
+		})), 3)
+	defer c.Close()
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, c.Set(ctx, fmt.Sprintf("key_%d", i), i, time.Minute))
+	}
+	// 访问 key_0, 使 key_1 成为最久未使用的
+	val, err := c.Get(ctx, "key_0")
+	require.NoError(t, err)
+	assert.Equal(t, 0, val)
+
+	require.NoError(t, c.Set(ctx, "key_3", 3, time.Minute))
+	assert.Equal(t, []string{"key_1"}, evicted)
+	_, err = c.Get(ctx, "key_1")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	// 更新已有的 key 不触发淘汰, 但会刷新顺序
+	require.NoError(t, c.Set(ctx, "key_2", 22, time.Minute))
+	assert.Equal(t, []string{"key_1"}, evicted)
+
+	require.NoError(t, c.Set(ctx, "key_4", 4, time.Minute))
+	require.NoError(t, c.Set(ctx, "key_5", 5, time.Minute))
+	assert.Equal(t, []string{"key_1", "key_0", "key_3"}, evicted)
+	assert.Equal(t, int32(3), c.cnt)
+}
+
+func TestMaxCntCache_OverCapacity(t *testing.T) {
+	c := NewMaxCntCache(NewMapCache(time.Minute), 1)
+	defer c.Close()
+	ctx := context.Background()
+	require.NoError(t, c.Set(ctx, "key1", 1, time.Minute))
+	err := c.Set(ctx, "key2", 2, time.Minute)
+	assert.Equal(t, ErrOverCapacity, err)
+}
+
+func TestLRUPolicy_Delete(t *testing.T) {
+	p := newLRUPolicy()
+	p.OnSet("key1")
+	p.OnSet("key2")
+	p.OnDelete("key1")
+	victim, ok := p.Victim()
+	require.True(t, ok)
+	assert.Equal(t, "key2", victim)
+	p.OnDelete("key2")
+	_, ok = p.Victim()
+	assert.False(t, ok)
+}
@@ -0,0 +1,5 @@
+-- ARGV 是需要置位的所有偏移量
+for i = 1, #ARGV do
+    redis.call('setbit', KEYS[1], ARGV[i], 1)
+end
+return 1
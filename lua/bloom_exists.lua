@@ -0,0 +1,7 @@
+for i = 1, #ARGV do
+    if redis.call('getbit', KEYS[1], ARGV[i]) == 0 then
+        --    有一位没有置位, 一定不存在
+        return 0
+    end
+end
+return 1
@@ -0,0 +1,6 @@
+local val = redis.call('get', KEYS[1])
+if val == false then
+    -- key 不存在
+    return false
+end
+return {val, redis.call('pttl', KEYS[1])}
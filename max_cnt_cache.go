@@ -2,15 +2,10 @@ package gcache
 
 import (
 	"context"
-	"errors"
 	"sync/atomic"
 	"time"
 )
 
-var (
-	errOverCapacity = errors.New("gcache 超过容量限制")
-)
-
 type MaxCntCache struct {
 	*MapCache
 	cnt    int32
@@ -23,22 +18,51 @@ func NewMaxCntCache(c *MapCache, maxCnt int32) *MaxCntCache {
 		maxCnt:   maxCnt,
 	}
 	origin := c.onEvicted
-	res.onEvicted = func(key string, val any) {
-		atomic.AddInt32(&res.cnt, -1)
+	res.onEvicted = func(key string, val any, reason EvictionReason) {
+		// 覆盖不会改变数量
+		if reason != EvictionReplaced {
+			atomic.AddInt32(&res.cnt, -1)
+		}
 		if origin != nil {
-			origin(key, val)
+			origin(key, val, reason)
 		}
 	}
 	return res
 }
 func (c *MaxCntCache) Set(ctx context.Context, key string, val any, expiration time.Duration) error {
+	if c.closing.Load() {
+		return ErrCacheClosed
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.add(key, val, expirationOf(c.strategy, key, expiration))
+}
+
+// SetMulti 和 Set 一样受容量限制, 超过容量的 key 会在结果中返回 ErrOverCapacity
+func (c *MaxCntCache) SetMulti(ctx context.Context, entries []Entry, expiration time.Duration) ([]Result, error) {
+	if c.closing.Load() {
+		return nil, ErrCacheClosed
+	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	res := make([]Result, len(entries))
+	for i, e := range entries {
+		res[i] = Result{Key: e.Key, Err: c.add(e.Key, e.Val, expirationOf(c.strategy, e.Key, expiration))}
+	}
+	return res, nil
+}
+
+// add 调用方需要持有写锁
+func (c *MaxCntCache) add(key string, val any, expiration time.Duration) error {
 	_, ok := c.data[key]
 	if !ok {
 		if c.cnt+1 > c.maxCnt {
-			// 可以在这里设计复杂的淘汰策略
-			return errOverCapacity
+			// 交给淘汰策略挑选一个 key 腾出位置
+			victim, ok := c.policy.Victim()
+			if !ok {
+				return ErrOverCapacity
+			}
+			c.delete(victim, EvictionCapacity)
 		}
 		c.cnt++
 	}
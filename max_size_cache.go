@@ -0,0 +1,127 @@
+package gcache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Sizer 值自己计算占用的字节数
+type Sizer interface {
+	Size() int64
+}
+
+// SizerFunc 计算 key 对应的值占用的字节数
+type SizerFunc func(key string, val any) int64
+
+// MaxSizeCache 按照值占用的字节数限制容量
+// 超过容量时交给 MapCache 的淘汰策略挑选 key, 直到能够放下新的值
+type MaxSizeCache struct {
+	*MapCache
+	sizer   SizerFunc
+	costs   map[string]int64
+	size    int64
+	maxSize int64
+}
+
+// NewMaxSizeCache sizer 为 nil 时, 值需要实现 Sizer, 或者是 string 和 []byte
+func NewMaxSizeCache(c *MapCache, maxSize int64, sizer SizerFunc) *MaxSizeCache {
+	res := &MaxSizeCache{
+		MapCache: c,
+		sizer:    sizer,
+		costs:    make(map[string]int64, 128),
+		maxSize:  maxSize,
+	}
+	origin := c.onEvicted
+	// onEvicted 总是在持有锁的时候调用
+	res.onEvicted = func(key string, val any, reason EvictionReason) {
+		// 覆盖时由 Set 自己更新大小
+		if reason != EvictionReplaced {
+			res.size -= res.costs[key]
+			delete(res.costs, key)
+		}
+		if origin != nil {
+			origin(key, val, reason)
+		}
+	}
+	return res
+}
+
+func (c *MaxSizeCache) Set(ctx context.Context, key string, val any, expiration time.Duration) error {
+	if c.closing.Load() {
+		return ErrCacheClosed
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.add(key, val, expirationOf(c.strategy, key, expiration))
+}
+
+// SetMulti 和 Set 一样受容量限制, 放不下的 key 会在结果中返回 ErrOverCapacity
+func (c *MaxSizeCache) SetMulti(ctx context.Context, entries []Entry, expiration time.Duration) ([]Result, error) {
+	if c.closing.Load() {
+		return nil, ErrCacheClosed
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res := make([]Result, len(entries))
+	for i, e := range entries {
+		res[i] = Result{Key: e.Key, Err: c.add(e.Key, e.Val, expirationOf(c.strategy, e.Key, expiration))}
+	}
+	return res, nil
+}
+
+// add 调用方需要持有写锁
+func (c *MaxSizeCache) add(key string, val any, expiration time.Duration) error {
+	cost, err := c.cost(key, val)
+	if err != nil {
+		return err
+	}
+	if cost > c.maxSize {
+		return fmt.Errorf("%w, key: %s, 大小: %d", ErrOverCapacity, key, cost)
+	}
+	for c.size-c.costs[key]+cost > c.maxSize {
+		victim, ok := c.policy.Victim()
+		if !ok {
+			return ErrOverCapacity
+		}
+		c.delete(victim, EvictionCapacity)
+	}
+	if err = c.set(key, val, expiration); err != nil {
+		return err
+	}
+	c.size += cost - c.costs[key]
+	c.costs[key] = cost
+	return nil
+}
+
+// Size 当前所有值占用的字节数
+func (c *MaxSizeCache) Size() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.size
+}
+
+// tombstoneCost 负缓存占位值占用的字节数
+const tombstoneCost int64 = 1
+
+// cost 装饰器写入的 envelope 按照里面的值计算大小
+func (c *MaxSizeCache) cost(key string, val any) (int64, error) {
+	val = unwrapEnvelope(val)
+	if _, ok := val.(tombstoneValue); ok {
+		// 负缓存的占位值不交给 sizer, 固定占用很小的空间
+		return tombstoneCost, nil
+	}
+	if c.sizer != nil {
+		return c.sizer(key, val), nil
+	}
+	switch v := val.(type) {
+	case Sizer:
+		return v.Size(), nil
+	case string:
+		return int64(len(v)), nil
+	case []byte:
+		return int64(len(v)), nil
+	default:
+		return 0, fmt.Errorf("%w, key: %s, 类型: %T", ErrUnknownSize, key, val)
+	}
+}
@@ -0,0 +1,150 @@
+package gcache
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type sizedVal int64
+
+func (s sizedVal) Size() int64 {
+	return int64(s)
+}
+
+func TestMaxSizeCache_Set(t *testing.T) {
+	testCases := []struct {
+		name  string
+		cache func() *MaxSizeCache
+
+		key string
+		val any
+
+		wantErr     error
+		wantSize    int64
+		wantEvicted []string
+	}{
+		{
+			name: "set value",
+			cache: func() *MaxSizeCache {
+				return NewMaxSizeCache(NewMapCache(time.Minute), 10, nil)
+			},
+			key:      "key1",
+			val:      "hello",
+			wantSize: 5,
+		},
+		{
+			name: "sizer interface",
+			cache: func() *MaxSizeCache {
+				return NewMaxSizeCache(NewMapCache(time.Minute), 10, nil)
+			},
+			key:      "key1",
+			val:      sizedVal(7),
+			wantSize: 7,
+		},
+		{
+			name: "sizer func",
+			cache: func() *MaxSizeCache {
+				return NewMaxSizeCache(NewMapCache(time.Minute), 10, func(key string, val any) int64 {
+					return 8
+				})
+			},
+			key:      "key1",
+			val:      123,
+			wantSize: 8,
+		},
+		{
+			name: "unknown size",
+			cache: func() *MaxSizeCache {
+				return NewMaxSizeCache(NewMapCache(time.Minute), 10, nil)
+			},
+			key:     "key1",
+			val:     123,
+			wantErr: fmt.Errorf("%w, key: %s, 类型: %T", ErrUnknownSize, "key1", 123),
+		},
+		{
+			name: "too large",
+			cache: func() *MaxSizeCache {
+				return NewMaxSizeCache(NewMapCache(time.Minute), 10, nil)
+			},
+			key:     "key1",
+			val:     []byte("hello world"),
+			wantErr: fmt.Errorf("%w, key: %s, 大小: %d", ErrOverCapacity, "key1", 11),
+		},
+		{
+			name: "over capacity without policy",
+			cache: func() *MaxSizeCache {
+				c := NewMaxSizeCache(NewMapCache(time.Minute), 10, nil)
+				require.NoError(t, c.Set(context.Background(), "key0", "hello", time.Minute))
+				return c
+			},
+			key:      "key1",
+			val:      "world!",
+			wantErr:  ErrOverCapacity,
+			wantSize: 5,
+		},
+		{
+			name: "replace value",
+			cache: func() *MaxSizeCache {
+				c := NewMaxSizeCache(NewMapCache(time.Minute), 10, nil)
+				require.NoError(t, c.Set(context.Background(), "key1", "hello", time.Minute))
+				return c
+			},
+			key:      "key1",
+			val:      "hello world",
+			wantErr:  fmt.Errorf("%w, key: %s, 大小: %d", ErrOverCapacity, "key1", 11),
+			wantSize: 5,
+		},
+		{
+			name: "evict until fit",
+			cache: func() *MaxSizeCache {
+				c := NewMaxSizeCache(NewMapCache(time.Minute, BuildMapCacheWithLRU()), 10, nil)
+				require.NoError(t, c.Set(context.Background(), "key0", "aaa", time.Minute))
+				require.NoError(t, c.Set(context.Background(), "key1", "bbb", time.Minute))
+				require.NoError(t, c.Set(context.Background(), "key2", "ccc", time.Minute))
+				return c
+			},
+			key:         "key3",
+			val:         "dddddd",
+			wantSize:    9,
+			wantEvicted: []string{"key0", "key1"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := tc.cache()
+			defer c.Close()
+			var evicted []string
+			origin := c.onEvicted
+			c.onEvicted = func(key string, val any, reason EvictionReason) {
+				if reason == EvictionCapacity {
+					evicted = append(evicted, key)
+				}
+				origin(key, val, reason)
+			}
+			err := c.Set(context.Background(), tc.key, tc.val, time.Minute)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantSize, c.Size())
+			assert.Equal(t, tc.wantEvicted, evicted)
+		})
+	}
+}
+
+func TestMaxSizeCache_Delete(t *testing.T) {
+	c := NewMaxSizeCache(NewMapCache(time.Minute), 10, nil)
+	defer c.Close()
+	ctx := context.Background()
+	require.NoError(t, c.Set(ctx, "key1", "hello", time.Minute))
+	require.NoError(t, c.Set(ctx, "key1", "hi", time.Minute))
+	assert.Equal(t, int64(2), c.Size())
+	require.NoError(t, c.Set(ctx, "key2", "world", time.Minute))
+	assert.Equal(t, int64(7), c.Size())
+	require.NoError(t, c.Delete(ctx, "key2"))
+	assert.Equal(t, int64(2), c.Size())
+	_, err := c.LoadAndDelete(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), c.Size())
+}
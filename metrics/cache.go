@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"context"
+	"github.com/NotFound1911/gcache"
+	"time"
+)
+
+const (
+	opGet           = "get"
+	opSet           = "set"
+	opDelete        = "delete"
+	opLoadAndDelete = "load_and_delete"
+)
+
+// Cache 统计被装饰的缓存的耗时, 命中率和错误
+type Cache struct {
+	gcache.Cache
+	name      string
+	collector *Collector
+}
+
+var _ gcache.Cache = (*Cache)(nil)
+
+func (c *Collector) WrapCache(name string, cache gcache.Cache) *Cache {
+	return &Cache{
+		Cache:     cache,
+		name:      name,
+		collector: c,
+	}
+}
+
+func (c *Cache) Set(ctx context.Context, key string, val any, expiration time.Duration) error {
+	start := time.Now()
+	err := c.Cache.Set(ctx, key, val, expiration)
+	c.observe(opSet, start, err)
+	return err
+}
+
+func (c *Cache) Get(ctx context.Context, key string) (any, error) {
+	start := time.Now()
+	val, err := c.Cache.Get(ctx, key)
+	c.observe(opGet, start, err)
+	// 其他错误只记录在 errors_total 中, 不计入命中率
+	switch {
+	case err == nil:
+		c.collector.gets.WithLabelValues(c.name, "hit").Inc()
+	case gcache.IsKeyNotFound(err):
+		c.collector.gets.WithLabelValues(c.name, "miss").Inc()
+	}
+	return val, err
+}
+
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	start := time.Now()
+	err := c.Cache.Delete(ctx, key)
+	c.observe(opDelete, start, err)
+	return err
+}
+
+func (c *Cache) LoadAndDelete(ctx context.Context, key string) (any, error) {
+	start := time.Now()
+	val, err := c.Cache.LoadAndDelete(ctx, key)
+	c.observe(opLoadAndDelete, start, err)
+	return val, err
+}
+
+func (c *Cache) observe(op string, start time.Time, err error) {
+	c.collector.opDuration.WithLabelValues(c.name, op).Observe(time.Since(start).Seconds())
+	if err != nil && !gcache.IsKeyNotFound(err) {
+		c.collector.errors.WithLabelValues(c.name, op).Inc()
+	}
+}
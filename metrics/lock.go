@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"context"
+	"github.com/NotFound1911/gcache"
+	"time"
+)
+
+// LockClient 统计加锁的耗时, 重试, 失败和自动续约失败
+type LockClient struct {
+	client    *gcache.Client
+	name      string
+	collector *Collector
+}
+
+func (c *Collector) WrapClient(name string, client *gcache.Client) *LockClient {
+	return &LockClient{
+		client:    client,
+		name:      name,
+		collector: c,
+	}
+}
+
+func (l *LockClient) Lock(ctx context.Context, key string, expiration time.Duration,
+	timeout time.Duration, retry gcache.RetryStrategy) (*gcache.Lock, error) {
+	start := time.Now()
+	lock, err := l.client.Lock(ctx, key, expiration, timeout, &countingRetry{
+		RetryStrategy: retry,
+		onRetry:       l.collector.lockRetries.WithLabelValues(l.name).Inc,
+	})
+	l.observe(start, err)
+	return lock, err
+}
+
+func (l *LockClient) TryLock(ctx context.Context, key string, expiration time.Duration) (*gcache.Lock, error) {
+	start := time.Now()
+	lock, err := l.client.TryLock(ctx, key, expiration)
+	l.observe(start, err)
+	return lock, err
+}
+
+// AutoRefresh 调用 lock.AutoRefreshWith, 每次续约失败都记录一次, 包括超时之后重试的续约
+func (l *LockClient) AutoRefresh(lock *gcache.Lock, interval time.Duration, timeout time.Duration) error {
+	return lock.AutoRefreshWith(interval, timeout, func(ctx context.Context) error {
+		err := lock.Refresh(ctx)
+		if err != nil {
+			l.collector.refreshFailures.WithLabelValues(l.name).Inc()
+		}
+		return err
+	})
+}
+
+func (l *LockClient) observe(start time.Time, err error) {
+	l.collector.lockDuration.WithLabelValues(l.name).Observe(time.Since(start).Seconds())
+	if err != nil {
+		l.collector.lockFailures.WithLabelValues(l.name).Inc()
+	}
+}
+
+// countingRetry 每次决定重试的时候回调 onRetry
+type countingRetry struct {
+	gcache.RetryStrategy
+	onRetry func()
+}
+
+func (c *countingRetry) Next() (time.Duration, bool) {
+	interval, ok := c.RetryStrategy.Next()
+	if ok {
+		c.onRetry()
+	}
+	return interval, ok
+}
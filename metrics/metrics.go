@@ -0,0 +1,84 @@
+// Package metrics 使用 Prometheus 统计缓存和分布式锁的指标
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "gcache"
+
+// Collector 保存所有的指标, 多个缓存和锁可以共用一个 Collector, 通过 name 标签区分
+// Collector 本身实现了 prometheus.Collector, 需要注册之后才能被采集
+type Collector struct {
+	opDuration *prometheus.HistogramVec
+	gets       *prometheus.CounterVec
+	errors     *prometheus.CounterVec
+
+	lockDuration    *prometheus.HistogramVec
+	lockRetries     *prometheus.CounterVec
+	lockFailures    *prometheus.CounterVec
+	refreshFailures *prometheus.CounterVec
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+func NewCollector() *Collector {
+	return &Collector{
+		opDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "operation_duration_seconds",
+			Help:      "缓存操作的耗时",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}, []string{"name", "op"}),
+		gets: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "gets_total",
+			Help:      "缓存查询次数, result 为 hit 或者 miss",
+		}, []string{"name", "result"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "errors_total",
+			Help:      "缓存操作出错的次数, 不包括未命中",
+		}, []string{"name", "op"}),
+		lockDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "lock_acquire_duration_seconds",
+			Help:      "加锁的耗时, 包括重试",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}, []string{"name"}),
+		lockRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "lock_retries_total",
+			Help:      "加锁重试的次数",
+		}, []string{"name"}),
+		lockFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "lock_failures_total",
+			Help:      "加锁失败的次数",
+		}, []string{"name"}),
+		refreshFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "lock_refresh_failures_total",
+			Help:      "自动续约失败的次数",
+		}, []string{"name"}),
+	}
+}
+
+func (c *Collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.opDuration, c.gets, c.errors,
+		c.lockDuration, c.lockRetries, c.lockFailures, c.refreshFailures,
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, col := range c.collectors() {
+		col.Describe(ch)
+	}
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, col := range c.collectors() {
+		col.Collect(ch)
+	}
+}
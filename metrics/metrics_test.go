@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/NotFound1911/gcache"
+	"github.com/NotFound1911/gcache/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	collector := NewCollector()
+	reg := prometheus.NewPedanticRegistry()
+	require.NoError(t, reg.Register(collector))
+
+	local := gcache.NewMapCache(time.Minute)
+	defer local.Close()
+	c := collector.WrapCache("local", local)
+	ctx := context.Background()
+	require.NoError(t, c.Set(ctx, "key1", "val1", time.Minute))
+	_, err := c.Get(ctx, "key1")
+	require.NoError(t, err)
+	_, err = c.Get(ctx, "key2")
+	require.Error(t, err)
+	_, err = c.LoadAndDelete(ctx, "key1")
+	require.NoError(t, err)
+	require.NoError(t, c.Delete(ctx, "key1"))
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(collector.gets.WithLabelValues("local", "hit")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(collector.gets.WithLabelValues("local", "miss")))
+	// 未命中不算错误
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.errors))
+	assert.Equal(t, 4, testutil.CollectAndCount(collector.opDuration))
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := mocks.NewMockCmdable(ctrl)
+	status := redis.NewStatusCmd(ctx)
+	status.SetErr(context.DeadlineExceeded)
+	cmd.EXPECT().Set(ctx, "key1", "val1", time.Minute).Return(status)
+	str := redis.NewStringCmd(ctx)
+	str.SetErr(context.DeadlineExceeded)
+	cmd.EXPECT().Get(ctx, "key1").Return(str)
+	rc := collector.WrapCache("redis", gcache.NewRedisCache(cmd))
+	err = rc.Set(ctx, "key1", "val1", time.Minute)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(collector.errors.WithLabelValues("redis", opSet)))
+	// 超时不是未命中
+	_, err = rc.Get(ctx, "key1")
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(collector.errors.WithLabelValues("redis", opGet)))
+	assert.Equal(t, float64(0), testutil.ToFloat64(collector.gets.WithLabelValues("redis", "miss")))
+
+	problems, err := testutil.GatherAndLint(reg)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestLockClient(t *testing.T) {
+	collector := NewCollector()
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := mocks.NewMockCmdable(ctrl)
+
+	locked := redis.NewCmd(ctx)
+	locked.SetVal("")
+	cmd.EXPECT().Eval(gomock.Any(), gomock.Any(), []string{"key1"}, gomock.Any(), gomock.Any()).
+		Times(3).Return(locked)
+	client := collector.WrapClient("lock", gcache.NewClient(cmd))
+	_, err := client.Lock(ctx, "key1", time.Minute, time.Second,
+		&gcache.FixedInterval{Interval: time.Millisecond, MaxCnt: 2})
+	assert.True(t, errors.Is(err, gcache.ErrFailedToPreemptLock))
+	assert.Equal(t, float64(2), testutil.ToFloat64(collector.lockRetries.WithLabelValues("lock")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(collector.lockFailures.WithLabelValues("lock")))
+
+	cmd.EXPECT().SetNX(ctx, "key2", gomock.Any(), time.Minute).Return(redis.NewBoolResult(true, nil))
+	lock, err := client.TryLock(ctx, "key2", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(collector.lockFailures.WithLabelValues("lock")))
+	assert.Equal(t, 1, testutil.CollectAndCount(collector.lockDuration))
+
+	// 超时之后重试的续约也算一次失败
+	timeout := redis.NewCmd(ctx)
+	timeout.SetErr(context.DeadlineExceeded)
+	notHold := redis.NewCmd(ctx)
+	notHold.SetVal(int64(0))
+	gomock.InOrder(
+		cmd.EXPECT().Eval(gomock.Any(), gomock.Any(), []string{"key2"}, gomock.Any(), gomock.Any()).Return(timeout),
+		cmd.EXPECT().Eval(gomock.Any(), gomock.Any(), []string{"key2"}, gomock.Any(), gomock.Any()).Return(notHold),
+	)
+	err = client.AutoRefresh(lock, time.Millisecond, time.Second)
+	assert.Equal(t, gcache.ErrLockNotHold, err)
+	assert.Equal(t, float64(2), testutil.ToFloat64(collector.refreshFailures.WithLabelValues("lock")))
+}
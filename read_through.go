@@ -5,50 +5,241 @@ import (
 	"errors"
 	"fmt"
 	"golang.org/x/sync/singleflight"
+	"sync/atomic"
 	"time"
 )
 
-var (
-	errFailedToRefreshCache = errors.New("gcache 刷新缓存失败")
-)
-
 type ReadTroughCache struct {
 	Cache
 	LoadFunc   func(ctx context.Context, key string) (any, error) // 需要初始化
 	Expiration time.Duration                                      // 过期时间
+	// NegativeExpiration LoadFunc 返回 ErrNotExist 时占位值的过期时间, 0 表示不缓存
+	NegativeExpiration time.Duration
+	// ExpirationStrategy 为空时直接使用 Expiration 和 NegativeExpiration
+	ExpirationStrategy ExpirationStrategy
+	// LoadTimeout 每次调用 LoadFunc 的超时时间, 0 表示使用默认的 10 秒
+	LoadTimeout time.Duration
+	g           singleflight.Group
+	stats       statsCounter
 }
 
 func (r *ReadTroughCache) Get(ctx context.Context, key string) (any, error) {
 	val, err := r.Cache.Get(ctx, key)
-	if err == errKeyNotFound { // 未找到
-		val, err = r.LoadFunc(ctx, key)
-		if err == nil {
-			errSet := r.Cache.Set(ctx, key, val, r.Expiration)
-			if errSet != nil {
-				return val, fmt.Errorf("%w, 原因: %s", errFailedToRefreshCache, errSet.Error())
-			}
-		}
+	if err == nil && isTombstone(val) {
+		return nil, fmt.Errorf("%w, key: %s", ErrKeyNotFound, key)
+	}
+	if errors.Is(err, ErrKeyNotFound) { // 未找到
+		val, err = r.sharedLoad(ctx, key)
 	}
 	return val, err
 }
 
+// sharedLoad 通过 singleflight 加载并写回缓存, 已经确认未命中的装饰器直接调用, 不用再读一次缓存
+func (r *ReadTroughCache) sharedLoad(ctx context.Context, key string) (any, error) {
+	return loadShared(ctx, &r.g, &r.stats, key, r.LoadTimeout, func(ctx context.Context) (any, error) {
+		return r.load(ctx, key)
+	})
+}
+
+// load 调用 LoadFunc 并写回缓存
+func (r *ReadTroughCache) load(ctx context.Context, key string) (any, error) {
+	val, err := r.LoadFunc(ctx, key)
+	r.stats.loaded(err)
+	if errors.Is(err, ErrNotExist) {
+		return nil, cacheNotExist(ctx, r.Cache, r.ExpirationStrategy, key, r.NegativeExpiration, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	errSet := setWithStrategy(ctx, r.Cache, r.ExpirationStrategy, key, val, r.Expiration)
+	if errSet != nil {
+		return val, fmt.Errorf("%w, 原因: %s", ErrFailedToRefreshCache, errSet.Error())
+	}
+	return val, nil
+}
+
+// LoadAndDelete 删除负缓存的占位值时和 Get 一样返回 ErrKeyNotFound
+func (r *ReadTroughCache) LoadAndDelete(ctx context.Context, key string) (any, error) {
+	return loadAndDeleteValue(ctx, r.Cache, key)
+}
+
+// Stats 底层缓存的统计数据, 加上加载的次数
+func (r *ReadTroughCache) Stats() Stats {
+	return loaderStats(r.Cache, &r.stats)
+}
+
+func (r *ReadTroughCache) ResetStats() {
+	resetLoaderStats(r.Cache, &r.stats)
+}
+
 type ReadThroughCacheV1[T any] struct {
 	Cache
 	LoadFunc   func(ctx context.Context, key string) (T, error)
 	Expiration time.Duration
-	g          singleflight.Group
+	// NegativeExpiration LoadFunc 返回 ErrNotExist 时占位值的过期时间, 0 表示不缓存
+	NegativeExpiration time.Duration
+	// ExpirationStrategy 为空时直接使用 Expiration 和 NegativeExpiration
+	ExpirationStrategy ExpirationStrategy
+	// LoadTimeout 每次调用 LoadFunc 的超时时间, 0 表示使用默认的 10 秒
+	LoadTimeout time.Duration
+	g           singleflight.Group
+	stats       statsCounter
 }
 
 func (r *ReadThroughCacheV1[T]) Get(ctx context.Context, key string) (T, error) {
 	val, err := r.Cache.Get(ctx, key)
-	if err == errKeyNotFound {
-		val, err = r.LoadFunc(ctx, key)
-		if err == nil {
-			errSet := r.Cache.Set(ctx, key, val, r.Expiration)
+	if err == nil && isTombstone(val) {
+		var t T
+		return t, fmt.Errorf("%w, key: %s", ErrKeyNotFound, key)
+	}
+	if errors.Is(err, ErrKeyNotFound) {
+		val, err = loadShared(ctx, &r.g, &r.stats, key, r.LoadTimeout, func(ctx context.Context) (any, error) {
+			loaded, err := r.LoadFunc(ctx, key)
+			r.stats.loaded(err)
+			if errors.Is(err, ErrNotExist) {
+				return nil, cacheNotExist(ctx, r.Cache, r.ExpirationStrategy, key, r.NegativeExpiration, err)
+			}
+			if err != nil {
+				return loaded, err
+			}
+			errSet := setWithStrategy(ctx, r.Cache, r.ExpirationStrategy, key, loaded, r.Expiration)
 			if errSet != nil {
-				return val.(T), fmt.Errorf("%w, 原因: %s", errFailedToRefreshCache, errSet.Error())
+				return loaded, fmt.Errorf("%w, 原因: %s", ErrFailedToRefreshCache, errSet.Error())
 			}
+			return loaded, nil
+		})
+		if val == nil {
+			var t T
+			return t, err
+		}
+		t, errType := assertType[T](key, val)
+		if errType != nil {
+			return t, errType
+		}
+		return t, err
+	}
+	if err != nil {
+		var t T
+		return t, err
+	}
+	return assertType[T](key, val)
+}
+
+// tombstoneValue 负缓存的占位值, 进程内使用单独的类型, 不会和真实的数据混淆
+type tombstoneValue struct{}
+
+var tombstone = tombstoneValue{}
+
+// tombstoneEncoding 占位值写入 redis 之后的内容, 以 \x00 开头避免和正常的字符串冲突
+const tombstoneEncoding = "\x00gcache:tombstone"
+
+func (tombstoneValue) MarshalBinary() ([]byte, error) {
+	return []byte(tombstoneEncoding), nil
+}
+
+func isTombstone(val any) bool {
+	switch v := val.(type) {
+	case tombstoneValue:
+		return true
+	case string:
+		return v == tombstoneEncoding
+	default:
+		return false
+	}
+}
+
+func loadAndDeleteValue(ctx context.Context, c Cache, key string) (any, error) {
+	val, err := c.LoadAndDelete(ctx, key)
+	if err == nil && isTombstone(val) {
+		return nil, fmt.Errorf("%w, key: %s", ErrKeyNotFound, key)
+	}
+	return val, err
+}
+
+// cacheNotExist 数据源中不存在的 key 写入占位值, 过期之前的 Get 直接返回 ErrKeyNotFound 而不会再次加载
+func cacheNotExist(ctx context.Context, c Cache, strategy ExpirationStrategy,
+	key string, expiration time.Duration, cause error) error {
+	if expiration > 0 {
+		// 写入失败只是少了一次保护, 依旧返回 ErrKeyNotFound, 同时带上写入失败的原因
+		if errSet := setWithStrategy(ctx, c, strategy, key, tombstone, expiration); errSet != nil {
+			return fmt.Errorf("%w, key: %s, 原因: %w, %w: %s",
+				ErrKeyNotFound, key, cause, ErrFailedToRefreshCache, errSet.Error())
 		}
 	}
-	return val.(T), err
+	return fmt.Errorf("%w, key: %s, 原因: %w", ErrKeyNotFound, key, cause)
+}
+
+// loadWaiting 正在 loadShared 中等待加载结果的调用方数量
+// 计数之前已经调用过 DoChan, 测试用它确认调用方都加入了同一次加载
+var loadWaiting atomic.Int64
+
+// defaultLoadTimeout 没有配置 LoadTimeout 时加载的超时时间
+const defaultLoadTimeout = time.Second * 10
+
+// loadShared 同一个 key 并发的加载只会执行一次, 其余的调用方等待并共享结果
+// 加载使用不会被取消的 context, 某个调用方取消只会让它自己提前返回, 不会让其他调用方失败
+func loadShared(ctx context.Context, g *singleflight.Group, stats *statsCounter, key string,
+	timeout time.Duration, load func(ctx context.Context) (any, error)) (any, error) {
+	if timeout <= 0 {
+		timeout = defaultLoadTimeout
+	}
+	executed := false
+	ch := g.DoChan(key, func() (any, error) {
+		executed = true
+		// 卡住的加载在超时之后结束, 不会让这个 key 之后的调用方一直等待
+		ctx, cancel := context.WithTimeout(detachedContext{Context: ctx}, timeout)
+		defer cancel()
+		return load(ctx)
+	})
+	loadWaiting.Add(1)
+	defer loadWaiting.Add(-1)
+	select {
+	case res := <-ch:
+		if !executed {
+			stats.loadsDeduplicated.Add(1)
+		}
+		return res.Val, res.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// detachedContext 保留 parent 中的值, 但是不会过期也不会被取消
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+// assertType 检查缓存中的值的类型, 类型不对的时候返回 ErrTypeMismatch 而不是 panic
+func assertType[T any](key string, val any) (T, error) {
+	res, ok := val.(T)
+	if !ok {
+		return res, fmt.Errorf("%w, key: %s, 期望类型: %T, 实际类型: %T", ErrTypeMismatch, key, res, val)
+	}
+	return res, nil
+}
+
+// LoadAndDelete 删除负缓存的占位值时和 Get 一样返回 ErrKeyNotFound
+func (r *ReadThroughCacheV1[T]) LoadAndDelete(ctx context.Context, key string) (any, error) {
+	return loadAndDeleteValue(ctx, r.Cache, key)
+}
+
+// Stats 底层缓存的统计数据, 加上加载的次数
+func (r *ReadThroughCacheV1[T]) Stats() Stats {
+	return loaderStats(r.Cache, &r.stats)
+}
+
+func (r *ReadThroughCacheV1[T]) ResetStats() {
+	resetLoaderStats(r.Cache, &r.stats)
 }
@@ -0,0 +1,391 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/NotFound1911/gcache/clock/clocktest"
+	"github.com/NotFound1911/gcache/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadTroughCache_Get(t *testing.T) {
+	testCases := []struct {
+		name  string
+		cache func(ctrl *gomock.Controller) Cache
+
+		wantVal  any
+		wantErr  error
+		wantLoad int
+	}{
+		{
+			name: "map cache hit",
+			cache: func(ctrl *gomock.Controller) Cache {
+				c := NewMapCache(time.Minute)
+				require.NoError(t, c.Set(context.Background(), "key1", "cached", time.Minute))
+				return c
+			},
+			wantVal: "cached",
+		},
+		{
+			name: "map cache miss",
+			cache: func(ctrl *gomock.Controller) Cache {
+				return NewMapCache(time.Minute)
+			},
+			wantVal:  "loaded",
+			wantLoad: 1,
+		},
+		{
+			name: "redis cache miss",
+			cache: func(ctrl *gomock.Controller) Cache {
+				cmd := mocks.NewMockCmdable(ctrl)
+				str := redis.NewStringCmd(context.Background())
+				str.SetErr(redis.Nil)
+				cmd.EXPECT().Get(gomock.Any(), "key1").Return(str)
+				status := redis.NewStatusCmd(context.Background())
+				status.SetVal("OK")
+				cmd.EXPECT().Set(gomock.Any(), "key1", "loaded", time.Minute).Return(status)
+				return NewRedisCache(cmd)
+			},
+			wantVal:  "loaded",
+			wantLoad: 1,
+		},
+		{
+			name: "redis cache error",
+			cache: func(ctrl *gomock.Controller) Cache {
+				cmd := mocks.NewMockCmdable(ctrl)
+				str := redis.NewStringCmd(context.Background())
+				str.SetErr(context.DeadlineExceeded)
+				cmd.EXPECT().Get(gomock.Any(), "key1").Return(str)
+				return NewRedisCache(cmd)
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			load := 0
+			c := &ReadTroughCache{
+				Cache: tc.cache(ctrl),
+				LoadFunc: func(ctx context.Context, key string) (any, error) {
+					load++
+					return "loaded", nil
+				},
+				Expiration: time.Minute,
+			}
+			val, err := c.Get(context.Background(), "key1")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantLoad, load)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantVal, val)
+		})
+	}
+}
+
+func TestReadThroughCacheV1_TypeMismatch(t *testing.T) {
+	local := NewMapCache(time.Minute)
+	defer local.Close()
+	require.NoError(t, local.Set(context.Background(), "key1", "not int", time.Minute))
+	c := &ReadThroughCacheV1[int]{
+		Cache: local,
+		LoadFunc: func(ctx context.Context, key string) (int, error) {
+			return 0, errors.New("unexpected load")
+		},
+		Expiration: time.Minute,
+	}
+	val, err := c.Get(context.Background(), "key1")
+	assert.True(t, errors.Is(err, ErrTypeMismatch))
+	assert.Equal(t, 0, val)
+
+	w := &WriteThroughCacheV1[int]{
+		Cache: local,
+		StoreFunc: func(ctx context.Context, key string, val int) error {
+			return nil
+		},
+	}
+	err = w.Set(context.Background(), "key2", "not int", time.Minute)
+	assert.True(t, errors.Is(err, ErrTypeMismatch))
+}
+
+// waitForLoadWaiters 等待 n 个调用方在 loadShared 中等待同一次加载的结果
+// 之后释放加载不会再有新的调用方发起加载
+func waitForLoadWaiters(t *testing.T, n int64) {
+	require.Eventually(t, func() bool {
+		return loadWaiting.Load() == n
+	}, time.Second, time.Millisecond)
+}
+
+func TestReadThroughCacheV1_Singleflight(t *testing.T) {
+	local := NewMapCache(time.Minute)
+	defer local.Close()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var load atomic.Int32
+	c := &ReadThroughCacheV1[string]{
+		Cache: local,
+		LoadFunc: func(ctx context.Context, key string) (string, error) {
+			load.Add(1)
+			close(started)
+			<-release
+			// 发起加载的调用方取消了, 加载本身不受影响
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			return "loaded", nil
+		},
+		Expiration: time.Minute,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := c.Get(ctx, "key1")
+		leaderErr <- err
+	}()
+	<-started
+
+	const followers = 10
+	var wg sync.WaitGroup
+	vals := make([]string, followers)
+	errs := make([]error, followers)
+	for i := 0; i < followers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			vals[i], errs[i] = c.Get(context.Background(), "key1")
+		}(i)
+	}
+	// 等待其他调用方都加入同一次加载
+	waitForLoadWaiters(t, followers+1)
+	cancel()
+	assert.Equal(t, context.Canceled, <-leaderErr)
+	close(release)
+	wg.Wait()
+
+	for i := 0; i < followers; i++ {
+		assert.NoError(t, errs[i])
+		assert.Equal(t, "loaded", vals[i])
+	}
+	assert.Equal(t, int32(1), load.Load())
+	stats := c.Stats()
+	assert.Equal(t, int64(1), stats.LoadSuccesses)
+	assert.Equal(t, int64(followers), stats.LoadsDeduplicated)
+	val, err := local.Get(context.Background(), "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "loaded", val)
+}
+
+func TestReadTroughCache_Singleflight(t *testing.T) {
+	local := NewMapCache(time.Minute)
+	defer local.Close()
+	release := make(chan struct{})
+	var load atomic.Int32
+	c := &ReadTroughCache{
+		Cache: local,
+		LoadFunc: func(ctx context.Context, key string) (any, error) {
+			load.Add(1)
+			<-release
+			return nil, errors.New("db error")
+		},
+		Expiration: time.Minute,
+	}
+	const callers = 10
+	var wg sync.WaitGroup
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = c.Get(context.Background(), "key1")
+		}(i)
+	}
+	waitForLoadWaiters(t, callers)
+	close(release)
+	wg.Wait()
+	for i := 0; i < callers; i++ {
+		assert.EqualError(t, errs[i], "db error")
+	}
+	assert.Equal(t, int32(1), load.Load())
+	stats := c.Stats()
+	assert.Equal(t, int64(1), stats.LoadFailures)
+	assert.Equal(t, int64(callers-1), stats.LoadsDeduplicated)
+}
+
+func TestReadTroughCache_LoadTimeout(t *testing.T) {
+	local := NewMapCache(time.Minute)
+	defer local.Close()
+	var traceID any
+	c := &ReadTroughCache{
+		Cache: local,
+		LoadFunc: func(ctx context.Context, key string) (any, error) {
+			traceID = ctx.Value(traceIDKey{})
+			// 卡住的加载在超时之后结束
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+		Expiration:  time.Minute,
+		LoadTimeout: time.Millisecond * 10,
+	}
+	ctx := context.WithValue(context.Background(), traceIDKey{}, "trace-1")
+	_, err := c.Get(ctx, "key1")
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, "trace-1", traceID)
+	// 超时之后新的调用方会重新加载
+	_, err = c.Get(context.Background(), "key1")
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, int64(2), c.Stats().LoadFailures)
+}
+
+func TestReadTroughCache_NegativeCache(t *testing.T) {
+	clk := clocktest.NewFakeClock(time.Now())
+	local := NewMapCache(time.Minute, BuildMapCacheWithClock(clk))
+	defer local.Close()
+	load := 0
+	c := &ReadTroughCache{
+		Cache: local,
+		LoadFunc: func(ctx context.Context, key string) (any, error) {
+			load++
+			return nil, fmt.Errorf("user %s: %w", key, ErrNotExist)
+		},
+		Expiration:         time.Minute,
+		NegativeExpiration: 10 * time.Second,
+	}
+	ctx := context.Background()
+	_, err := c.Get(ctx, "key1")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.ErrorIs(t, err, ErrNotExist)
+	// 占位值过期之前不会再次加载
+	for i := 0; i < 3; i++ {
+		_, err = c.Get(ctx, "key1")
+		assert.Equal(t, fmt.Errorf("%w, key: %s", ErrKeyNotFound, "key1"), err)
+	}
+	assert.Equal(t, 1, load)
+
+	clk.Advance(11 * time.Second)
+	_, err = c.Get(ctx, "key1")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.Equal(t, 2, load)
+
+	// 不配置 NegativeExpiration 时不缓存
+	c.NegativeExpiration = 0
+	_, err = c.Get(ctx, "key2")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	_, err = c.Get(ctx, "key2")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.Equal(t, 4, load)
+}
+
+func TestReadTroughCache_NegativeCacheMaxSize(t *testing.T) {
+	var sized []any
+	local := NewMaxSizeCache(NewMapCache(time.Minute), 10, func(key string, val any) int64 {
+		sized = append(sized, val)
+		return int64(len(val.(string)))
+	})
+	defer local.Close()
+	load := 0
+	c := &ReadTroughCache{
+		Cache: local,
+		LoadFunc: func(ctx context.Context, key string) (any, error) {
+			load++
+			return nil, ErrNotExist
+		},
+		Expiration:         time.Minute,
+		NegativeExpiration: time.Minute,
+	}
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		_, err := c.Get(ctx, "key1")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+	}
+	// 占位值不经过 sizer, 固定占用 tombstoneCost
+	assert.Equal(t, 1, load)
+	assert.Empty(t, sized)
+	assert.Equal(t, tombstoneCost, local.Size())
+
+	// 写入占位值失败的时候带上原因
+	c.Cache = &setErrCache{Cache: local}
+	_, err := c.Get(ctx, "key2")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.ErrorIs(t, err, ErrFailedToRefreshCache)
+}
+
+type setErrCache struct {
+	Cache
+}
+
+func (c *setErrCache) Set(ctx context.Context, key string, val any, expiration time.Duration) error {
+	return errors.New("mock set error")
+}
+
+func TestReadThroughCacheV1_NegativeCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := mocks.NewMockCmdable(ctrl)
+	miss := redis.NewStringCmd(context.Background())
+	miss.SetErr(redis.Nil)
+	status := redis.NewStatusCmd(context.Background())
+	status.SetVal("OK")
+	hit := redis.NewStringCmd(context.Background())
+	hit.SetVal(tombstoneEncoding)
+	gomock.InOrder(
+		cmd.EXPECT().Get(gomock.Any(), "key1").Return(miss),
+		cmd.EXPECT().Set(gomock.Any(), "key1", tombstone, 5*time.Second).Return(status),
+		cmd.EXPECT().Get(gomock.Any(), "key1").Return(hit),
+	)
+	load := 0
+	c := &ReadThroughCacheV1[int]{
+		Cache: NewRedisCache(cmd),
+		LoadFunc: func(ctx context.Context, key string) (int, error) {
+			load++
+			return 0, ErrNotExist
+		},
+		Expiration:         time.Minute,
+		NegativeExpiration: 5 * time.Second,
+	}
+	for i := 0; i < 2; i++ {
+		val, err := c.Get(context.Background(), "key1")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+		assert.Equal(t, 0, val)
+	}
+	assert.Equal(t, 1, load)
+}
+
+func TestReadTroughCache_Tombstone(t *testing.T) {
+	ctx := context.Background()
+	local := NewMapCache(time.Minute)
+	defer local.Close()
+	c := &ReadTroughCache{
+		Cache: local,
+		LoadFunc: func(ctx context.Context, key string) (any, error) {
+			return nil, ErrNotExist
+		},
+		Expiration:         time.Minute,
+		NegativeExpiration: time.Minute,
+	}
+	_, err := c.Get(ctx, "key1")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	// 删除占位值不会把它当作数据返回
+	_, err = c.LoadAndDelete(ctx, "key1")
+	assert.Equal(t, fmt.Errorf("%w, key: %s", ErrKeyNotFound, "key1"), err)
+
+	// 和占位值内容相同的普通字符串是正常的数据
+	require.NoError(t, local.Set(ctx, "key2", "gcache:tombstone", time.Minute))
+	val, err := c.Get(ctx, "key2")
+	require.NoError(t, err)
+	assert.Equal(t, "gcache:tombstone", val)
+
+	data, err := tombstone.MarshalBinary()
+	require.NoError(t, err)
+	assert.True(t, isTombstone(string(data)))
+}
@@ -2,20 +2,25 @@ package gcache
 
 import (
 	"context"
+	_ "embed"
 	"errors"
 	"fmt"
 	"github.com/redis/go-redis/v9"
 	"time"
 )
 
-// $GOPATH/bin/mockgen -destination=mocks/mock_redis_cmdable.gen.go -package=mocks github.com/redis/go-redis/v9 Cmdable
-
 var (
-	errFailedToSetCache = errors.New("gcache: 写入 redis 失败")
+	//go:embed lua/get_with_ttl.lua
+	luaGetWithTTL string
 )
 
+var _ TTLCache = (*RedisCache)(nil)
+
+// $GOPATH/bin/mockgen -destination=mocks/mock_redis_cmdable.gen.go -package=mocks github.com/redis/go-redis/v9 Cmdable
+
 type RedisCache struct {
 	client redis.Cmdable
+	stats  statsCounter
 }
 
 func NewRedisCache(client redis.Cmdable) *RedisCache {
@@ -30,21 +35,154 @@ func (r *RedisCache) Set(ctx context.Context, key string, val any, expiration ti
 		return err
 	}
 	if res != "OK" {
-		return fmt.Errorf("%w, 返回信息: %s", errFailedToSetCache, res)
+		return fmt.Errorf("%w, 返回信息: %s", ErrFailedToSetCache, res)
 	}
+	r.stats.sets.Add(1)
 	return nil
 }
 
 func (r *RedisCache) Get(ctx context.Context, key string) (any, error) {
-	return r.client.Get(ctx, key).Result()
+	res, err := r.client.Get(ctx, key).Result()
+	switch {
+	case err == nil:
+		r.stats.hits.Add(1)
+	case errors.Is(err, redis.Nil):
+		r.stats.misses.Add(1)
+		return nil, fmt.Errorf("%w, key: %s", ErrKeyNotFound, key)
+	default:
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetWithTTL 在一个脚本中执行 GET 和 PTTL, 读到的值和剩余过期时间是一致的
+func (r *RedisCache) GetWithTTL(ctx context.Context, key string) (any, time.Duration, error) {
+	res, err := r.client.Eval(ctx, luaGetWithTTL, []string{key}).Slice()
+	switch {
+	case err == nil:
+	case errors.Is(err, redis.Nil):
+		r.stats.misses.Add(1)
+		return nil, 0, fmt.Errorf("%w, key: %s", ErrKeyNotFound, key)
+	default:
+		return nil, 0, err
+	}
+	// 脚本返回值和剩余的毫秒数, -1 表示没有设置过期时间
+	var ttl int64
+	ok := len(res) == 2
+	if ok {
+		ttl, ok = res[1].(int64)
+	}
+	if !ok {
+		return nil, 0, fmt.Errorf("gcache: 无法解析 GetWithTTL 的返回值 %v", res)
+	}
+	r.stats.hits.Add(1)
+	if ttl < 0 {
+		return res[0], NoExpiration, nil
+	}
+	return res[0], time.Duration(ttl) * time.Millisecond, nil
 }
 
 func (r *RedisCache) Delete(ctx context.Context, key string) error {
 	_, err := r.client.Del(ctx, key).Result()
+	if err == nil {
+		r.stats.deletes.Add(1)
+	}
 	return err
 }
 
+// GetMulti 使用 MGET 一次取回所有的 key
+func (r *RedisCache) GetMulti(ctx context.Context, keys []string) ([]Result, error) {
+	// 没有参数的 MGET 会被 redis 拒绝
+	if len(keys) == 0 {
+		return []Result{}, nil
+	}
+	vals, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Result, len(keys))
+	for i, key := range keys {
+		res[i].Key = key
+		if vals[i] == nil {
+			r.stats.misses.Add(1)
+			res[i].Err = fmt.Errorf("%w, key: %s", ErrKeyNotFound, key)
+			continue
+		}
+		r.stats.hits.Add(1)
+		res[i].Val = vals[i]
+	}
+	return res, nil
+}
+
+// SetMulti 使用 pipeline 写入, 每个 key 的过期时间相同
+func (r *RedisCache) SetMulti(ctx context.Context, entries []Entry, expiration time.Duration) ([]Result, error) {
+	if len(entries) == 0 {
+		return []Result{}, nil
+	}
+	cmds := make([]*redis.StatusCmd, len(entries))
+	// pipeline 中每个命令的错误都会记录在命令上, 所以不需要处理 Pipelined 返回的错误
+	_, _ = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, e := range entries {
+			cmds[i] = pipe.Set(ctx, e.Key, e.Val, expiration)
+		}
+		return nil
+	})
+	res := make([]Result, len(entries))
+	for i, e := range entries {
+		res[i].Key = e.Key
+		val, err := cmds[i].Result()
+		switch {
+		case err != nil:
+			res[i].Err = err
+		case val != "OK":
+			res[i].Err = fmt.Errorf("%w, 返回信息: %s", ErrFailedToSetCache, val)
+		default:
+			r.stats.sets.Add(1)
+		}
+	}
+	return res, nil
+}
+
+// DeleteMulti 使用 pipeline 删除, 不存在的 key 不算失败
+func (r *RedisCache) DeleteMulti(ctx context.Context, keys []string) ([]Result, error) {
+	if len(keys) == 0 {
+		return []Result{}, nil
+	}
+	cmds := make([]*redis.IntCmd, len(keys))
+	_, _ = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Del(ctx, key)
+		}
+		return nil
+	})
+	res := make([]Result, len(keys))
+	for i, key := range keys {
+		res[i] = Result{Key: key, Err: cmds[i].Err()}
+		if res[i].Err == nil {
+			r.stats.deletes.Add(1)
+		}
+	}
+	return res, nil
+}
+
+// Stats Redis 的过期和淘汰发生在服务端, 所以只统计客户端能看到的操作, 也不统计 Entries
+func (r *RedisCache) Stats() Stats {
+	return r.stats.snapshot()
+}
+
+func (r *RedisCache) ResetStats() {
+	r.stats.reset()
+}
+
+// LoadAndDelete 使用 GETDEL, 需要 Redis 6.2 及以上版本
 func (r *RedisCache) LoadAndDelete(ctx context.Context, key string) (any, error) {
-	//TODO implement me
-	panic("implement me")
+	res, err := r.client.GetDel(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w, key: %s", ErrKeyNotFound, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.stats.deletes.Add(1)
+	return res, nil
 }
@@ -123,3 +123,72 @@ func TestRedisCache_e2e_Get(t *testing.T) {
 		})
 	}
 }
+
+func TestRedisCache_e2e_Multi(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	c := NewRedisCache(rdb)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	res, err := c.SetMulti(ctx, []Entry{{Key: "multi1", Val: "val1"}, {Key: "multi2", Val: "val2"}}, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, []Result{{Key: "multi1"}, {Key: "multi2"}}, res)
+
+	res, err = c.GetMulti(ctx, []string{"multi1", "multi3", "multi2"})
+	require.NoError(t, err)
+	assert.Equal(t, "val1", res[0].Val)
+	assert.ErrorIs(t, res[1].Err, ErrKeyNotFound)
+	assert.Equal(t, "val2", res[2].Val)
+
+	res, err = c.DeleteMulti(ctx, []string{"multi1", "multi2", "multi3"})
+	require.NoError(t, err)
+	for _, r := range res {
+		assert.NoError(t, r.Err)
+	}
+	cnt, err := rdb.Exists(ctx, "multi1", "multi2").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), cnt)
+}
+
+func TestRedisCache_e2e_GetWithTTL(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	c := NewRedisCache(rdb)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	defer rdb.Del(ctx, "ttl1", "ttl2")
+	require.NoError(t, c.Set(ctx, "ttl1", "val1", time.Minute))
+	require.NoError(t, c.Set(ctx, "ttl2", "val2", 0))
+
+	val, ttl, err := c.GetWithTTL(ctx, "ttl1")
+	require.NoError(t, err)
+	assert.Equal(t, "val1", val)
+	assert.True(t, ttl > 0 && ttl <= time.Minute)
+	val, ttl, err = c.GetWithTTL(ctx, "ttl2")
+	require.NoError(t, err)
+	assert.Equal(t, "val2", val)
+	assert.Equal(t, NoExpiration, ttl)
+	_, _, err = c.GetWithTTL(ctx, "ttl3")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestRedisBloomFilter_e2e(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	defer rdb.Del(ctx, "bloom_e2e")
+	// 两个实例共享同一个过滤器
+	b1 := NewRedisBloomFilter(rdb, "bloom_e2e", 1000, 0.01)
+	b2 := NewRedisBloomFilter(rdb, "bloom_e2e", 1000, 0.01)
+	require.NoError(t, b1.Add(ctx, "key1"))
+	ok, err := b2.MightContain(ctx, "key1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = b2.MightContain(ctx, "key2")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -5,6 +5,7 @@ import (
 	_ "embed"
 	"errors"
 	"fmt"
+	"github.com/NotFound1911/gcache/clock"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"golang.org/x/sync/singleflight"
@@ -25,14 +26,29 @@ var (
 	luaLock string
 )
 
+type ClientOption func(c *Client)
+
 type Client struct {
 	client redis.Cmdable
 	g      singleflight.Group
+	clock  clock.Clock
 }
 
-func NewClient(client redis.Cmdable) *Client {
-	return &Client{
+func NewClient(client redis.Cmdable, opts ...ClientOption) *Client {
+	res := &Client{
 		client: client,
+		clock:  clock.New(),
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// BuildClientWithClock 使用自定义的时钟控制重试间隔和自动续约
+func BuildClientWithClock(c clock.Clock) ClientOption {
+	return func(client *Client) {
+		client.clock = c
 	}
 }
 func (c *Client) SingleflightLock(ctx context.Context, key string, expiration time.Duration,
@@ -59,7 +75,7 @@ func (c *Client) SingleflightLock(ctx context.Context, key string, expiration ti
 }
 func (c *Client) Lock(ctx context.Context, key string, expiration time.Duration,
 	timeout time.Duration, retry RetryStrategy) (*Lock, error) {
-	var timer *time.Timer
+	var timer clock.Timer
 	val := uuid.New().String()
 	for {
 		// 重试
@@ -76,6 +92,7 @@ func (c *Client) Lock(ctx context.Context, key string, expiration time.Duration,
 				value:      val,
 				expiration: expiration,
 				unlockChan: make(chan struct{}, 1),
+				clock:      c.clock,
 			}, nil
 		}
 		interval, ok := retry.Next()
@@ -83,17 +100,17 @@ func (c *Client) Lock(ctx context.Context, key string, expiration time.Duration,
 			return nil, fmt.Errorf("%w, 超出重试限制", ErrFailedToPreemptLock)
 		}
 		if timer == nil {
-			timer = time.NewTimer(interval)
+			timer = c.clock.NewTimer(interval)
+			defer timer.Stop()
 		} else {
 			timer.Reset(interval)
 		}
 		select {
-		case <-timer.C:
+		case <-timer.C():
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		}
 	}
-	return nil, nil
 }
 func (c *Client) TryLock(ctx context.Context, key string, expiration time.Duration) (*Lock, error) {
 	val := uuid.New().String()
@@ -110,6 +127,8 @@ func (c *Client) TryLock(ctx context.Context, key string, expiration time.Durati
 		key:        key,
 		value:      val,
 		expiration: expiration,
+		unlockChan: make(chan struct{}, 1),
+		clock:      c.clock,
 	}, err
 }
 
@@ -119,6 +138,7 @@ type Lock struct {
 	value      string
 	expiration time.Duration
 	unlockChan chan struct{}
+	clock      clock.Clock
 }
 
 func (l *Lock) Unlock(ctx context.Context) error {
@@ -151,13 +171,25 @@ func (l *Lock) Refresh(ctx context.Context) error {
 }
 
 func (l *Lock) AutoRefresh(interval time.Duration, timeout time.Duration) error {
+	return l.AutoRefreshWith(interval, timeout, l.Refresh)
+}
+
+// AutoRefreshWith 和 AutoRefresh 一样, 但是每次续约调用 refresh
+// 装饰器可以传入自己的 Refresh, 为自动续约加上监控和链路追踪
+func (l *Lock) AutoRefreshWith(interval time.Duration, timeout time.Duration,
+	refresh func(ctx context.Context) error) error {
 	timeoutChan := make(chan struct{}, 1)
-	ticker := time.NewTicker(interval)
+	clk := l.clock
+	if clk == nil {
+		clk = clock.New()
+	}
+	ticker := clk.NewTicker(interval)
+	defer ticker.Stop()
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
 			ctx, cancel := context.WithTimeout(context.Background(), timeout)
-			err := l.Refresh(ctx)
+			err := refresh(ctx)
 			cancel()
 			if errors.Is(err, context.DeadlineExceeded) {
 				timeoutChan <- struct{}{}
@@ -168,7 +200,7 @@ func (l *Lock) AutoRefresh(interval time.Duration, timeout time.Duration) error
 			}
 		case <-timeoutChan:
 			ctx, cancel := context.WithTimeout(context.Background(), timeout)
-			err := l.Refresh(ctx)
+			err := refresh(ctx)
 			cancel()
 			if errors.Is(err, context.DeadlineExceeded) {
 				timeoutChan <- struct{}{}
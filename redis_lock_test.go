@@ -2,6 +2,8 @@ package gcache
 
 import (
 	"context"
+	"fmt"
+	"github.com/NotFound1911/gcache/clock/clocktest"
 	"github.com/NotFound1911/gcache/mocks"
 	"github.com/golang/mock/gomock"
 	"github.com/redis/go-redis/v9"
@@ -59,6 +61,73 @@ func TestClient_Lock(t *testing.T) {
 	}
 }
 
+func TestClient_LockRetry(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) redis.Cmdable
+
+		retry RetryStrategy
+
+		wantErr error
+	}{
+		{
+			name: "locked after retry",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := mocks.NewMockCmdable(ctrl)
+				first := redis.NewCmd(context.Background())
+				first.SetVal("")
+				second := redis.NewCmd(context.Background())
+				second.SetVal("OK")
+				gomock.InOrder(
+					cmd.EXPECT().Eval(gomock.Any(), luaLock, []string{"key1"}, gomock.Any(), float64(60)).Return(first),
+					cmd.EXPECT().Eval(gomock.Any(), luaLock, []string{"key1"}, gomock.Any(), float64(60)).Return(second),
+				)
+				return cmd
+			},
+			retry: &FixedInterval{Interval: time.Minute, MaxCnt: 1},
+		},
+		{
+			name: "out of retry",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := mocks.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal("")
+				cmd.EXPECT().Eval(gomock.Any(), luaLock, []string{"key1"}, gomock.Any(), float64(60)).
+					Times(2).Return(res)
+				return cmd
+			},
+			retry:   &FixedInterval{Interval: time.Minute, MaxCnt: 1},
+			wantErr: fmt.Errorf("%w, 超出重试限制", ErrFailedToPreemptLock),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			clk := clocktest.NewFakeClock(time.Now())
+			client := NewClient(tc.mock(ctrl), BuildClientWithClock(clk))
+			type result struct {
+				l   *Lock
+				err error
+			}
+			resChan := make(chan result, 1)
+			go func() {
+				l, err := client.Lock(context.Background(), "key1", time.Minute, time.Second, tc.retry)
+				resChan <- result{l: l, err: err}
+			}()
+			// 等待第一次抢锁失败之后开始等待重试
+			clk.BlockUntil(1)
+			clk.Advance(time.Minute)
+			res := <-resChan
+			assert.Equal(t, tc.wantErr, res.err)
+			if res.err != nil {
+				return
+			}
+			assert.Equal(t, "key1", res.l.key)
+		})
+	}
+}
+
 func TestLock_Unlock(t *testing.T) {
 	testCases := []struct {
 		name string
@@ -62,7 +62,7 @@ func TestRedisCache_Set(t *testing.T) {
 			key:        "key",
 			val:        "val",
 			expiration: time.Second,
-			wantErr:    fmt.Errorf("%w, 返回信息: %s", errFailedToSetCache, "NO OK"),
+			wantErr:    fmt.Errorf("%w, 返回信息: %s", ErrFailedToSetCache, "NO OK"),
 		},
 	}
 	for _, tc := range testCases {
@@ -113,6 +113,19 @@ func TestRedisCache_Get(t *testing.T) {
 			key:     "key",
 			wantErr: context.DeadlineExceeded,
 		},
+		{
+			name: "key not found",
+			mock: func(controller *gomock.Controller) redis.Cmdable {
+				cmd := mocks.NewMockCmdable(controller)
+				str := redis.NewStringCmd(context.Background())
+				str.SetErr(redis.Nil)
+				cmd.EXPECT().
+					Get(context.Background(), "key").Return(str)
+				return cmd
+			},
+			key:     "key",
+			wantErr: fmt.Errorf("%w, key: %s", ErrKeyNotFound, "key"),
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -128,3 +141,156 @@ func TestRedisCache_Get(t *testing.T) {
 		})
 	}
 }
+
+func TestRedisCache_GetWithTTL(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		mock func(controller *gomock.Controller) redis.Cmdable
+
+		key string
+
+		wantErr error
+		wantVal any
+		wantTTL time.Duration
+	}{
+		{
+			name: "get value",
+			mock: func(controller *gomock.Controller) redis.Cmdable {
+				cmd := mocks.NewMockCmdable(controller)
+				res := redis.NewCmd(context.Background())
+				res.SetVal([]any{"val", int64(1500)})
+				cmd.EXPECT().
+					Eval(context.Background(), luaGetWithTTL, []string{"key"}).Return(res)
+				return cmd
+			},
+			key:     "key",
+			wantVal: "val",
+			wantTTL: time.Millisecond * 1500,
+		},
+		{
+			name: "no expiration",
+			mock: func(controller *gomock.Controller) redis.Cmdable {
+				cmd := mocks.NewMockCmdable(controller)
+				res := redis.NewCmd(context.Background())
+				res.SetVal([]any{"val", int64(-1)})
+				cmd.EXPECT().
+					Eval(context.Background(), luaGetWithTTL, []string{"key"}).Return(res)
+				return cmd
+			},
+			key:     "key",
+			wantVal: "val",
+			wantTTL: NoExpiration,
+		},
+		{
+			name: "key not found",
+			mock: func(controller *gomock.Controller) redis.Cmdable {
+				cmd := mocks.NewMockCmdable(controller)
+				res := redis.NewCmd(context.Background())
+				res.SetErr(redis.Nil)
+				cmd.EXPECT().
+					Eval(context.Background(), luaGetWithTTL, []string{"key"}).Return(res)
+				return cmd
+			},
+			key:     "key",
+			wantErr: fmt.Errorf("%w, key: %s", ErrKeyNotFound, "key"),
+		},
+		{
+			name: "timeout",
+			mock: func(controller *gomock.Controller) redis.Cmdable {
+				cmd := mocks.NewMockCmdable(controller)
+				res := redis.NewCmd(context.Background())
+				res.SetErr(context.DeadlineExceeded)
+				cmd.EXPECT().
+					Eval(context.Background(), luaGetWithTTL, []string{"key"}).Return(res)
+				return cmd
+			},
+			key:     "key",
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			c := NewRedisCache(tc.mock(ctrl))
+			val, ttl, err := c.GetWithTTL(context.Background(), tc.key)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantVal, val)
+			assert.Equal(t, tc.wantTTL, ttl)
+		})
+	}
+}
+
+func TestRedisCache_LoadAndDelete(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		mock func(controller *gomock.Controller) redis.Cmdable
+
+		key string
+
+		wantErr error
+		wantVal any
+	}{
+		{
+			name: "load and delete",
+			mock: func(controller *gomock.Controller) redis.Cmdable {
+				cmd := mocks.NewMockCmdable(controller)
+				str := redis.NewStringCmd(context.Background())
+				str.SetVal("val")
+				cmd.EXPECT().
+					GetDel(context.Background(), "key").Return(str)
+				return cmd
+			},
+			key:     "key",
+			wantVal: "val",
+		},
+		{
+			name: "key not found",
+			mock: func(controller *gomock.Controller) redis.Cmdable {
+				cmd := mocks.NewMockCmdable(controller)
+				str := redis.NewStringCmd(context.Background())
+				str.SetErr(redis.Nil)
+				cmd.EXPECT().
+					GetDel(context.Background(), "key").Return(str)
+				return cmd
+			},
+			key:     "key",
+			wantErr: fmt.Errorf("%w, key: %s", ErrKeyNotFound, "key"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			c := NewRedisCache(tc.mock(ctrl))
+			val, err := c.LoadAndDelete(context.Background(), tc.key)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantVal, val)
+		})
+	}
+}
+
+func TestRedisCache_MultiEmpty(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// 没有 key 的时候不会访问 redis
+	c := NewRedisCache(mocks.NewMockCmdable(ctrl))
+	ctx := context.Background()
+	res, err := c.GetMulti(ctx, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []Result{}, res)
+	res, err = c.SetMulti(ctx, []Entry{}, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, []Result{}, res)
+	res, err = c.DeleteMulti(ctx, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []Result{}, res)
+}
@@ -0,0 +1,166 @@
+package gcache
+
+import (
+	"context"
+	"github.com/NotFound1911/gcache/clock"
+	"sync"
+	"time"
+)
+
+type RefreshAheadOption func(r *RefreshAheadCache)
+
+// RefreshAheadCache 提前刷新
+// key 剩余的过期时间少于 ratio 比例时, Get 会在后台重新加载, 加载完成之前继续返回旧的值
+// 只有被访问的 key 才会刷新, 长时间没有访问的 key 依旧会过期
+type RefreshAheadCache struct {
+	*ReadTroughCache
+	ratio     float64
+	clock     clock.Clock
+	refresher *refresher
+
+	mu      sync.Mutex
+	entries map[string]*refreshEntry
+	tracks  int
+}
+
+type refreshEntry struct {
+	deadline  time.Time
+	refreshAt time.Time
+}
+
+// NewRefreshAheadCache 加载和写回缓存的逻辑和 ReadTroughCache 一致,
+// 可以通过返回值的 ReadTroughCache 字段设置 NegativeExpiration 和 ExpirationStrategy
+func NewRefreshAheadCache(c Cache, loadFunc func(ctx context.Context, key string) (any, error),
+	expiration time.Duration, opts ...RefreshAheadOption) *RefreshAheadCache {
+	res := &RefreshAheadCache{
+		ratio:     0.2,
+		clock:     clock.New(),
+		refresher: newRefresher(),
+		entries:   make(map[string]*refreshEntry, 128),
+	}
+	res.ReadTroughCache = &ReadTroughCache{
+		Cache:      &deadlineCache{Cache: c, r: res},
+		LoadFunc:   loadFunc,
+		Expiration: expiration,
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// BuildRefreshAheadWithRatio 剩余过期时间少于 ratio 比例时开始刷新, 默认 0.2
+func BuildRefreshAheadWithRatio(ratio float64) RefreshAheadOption {
+	return func(r *RefreshAheadCache) {
+		r.ratio = ratio
+	}
+}
+
+// BuildRefreshAheadWithConcurrency 同时进行的后台刷新数量, 默认 10, 超过的 key 等下一次 Get 再尝试
+func BuildRefreshAheadWithConcurrency(concurrency int) RefreshAheadOption {
+	return func(r *RefreshAheadCache) {
+		r.refresher.sem = make(chan struct{}, concurrency)
+	}
+}
+
+// BuildRefreshAheadWithTimeout 后台刷新的超时时间, 默认 10 秒
+func BuildRefreshAheadWithTimeout(timeout time.Duration) RefreshAheadOption {
+	return func(r *RefreshAheadCache) {
+		r.refresher.timeout = timeout
+	}
+}
+
+func BuildRefreshAheadWithClock(c clock.Clock) RefreshAheadOption {
+	return func(r *RefreshAheadCache) {
+		r.clock = c
+	}
+}
+
+func (r *RefreshAheadCache) Get(ctx context.Context, key string) (any, error) {
+	val, err := r.ReadTroughCache.Get(ctx, key)
+	if err == nil {
+		r.maybeRefresh(key)
+	}
+	return val, err
+}
+
+// maybeRefresh 快要过期的 key 在后台重新加载
+func (r *RefreshAheadCache) maybeRefresh(key string) {
+	now := r.clock.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.entries[key]
+	if !ok || now.Before(e.refreshAt) {
+		return
+	}
+	if !now.Before(e.deadline) {
+		// 已经过期, 下一次 Get 会同步加载
+		delete(r.entries, key)
+		return
+	}
+	// 刷新成功之后 track 会更新 refreshAt, 失败的话下一次 Get 再尝试
+	r.refresher.refresh(key, r.ReadTroughCache)
+}
+
+// track 记录 key 的过期时间, 写入成功之后调用
+func (r *RefreshAheadCache) track(key string, expiration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if expiration <= 0 {
+		delete(r.entries, key)
+		return
+	}
+	now := r.clock.Now()
+	r.entries[key] = &refreshEntry{
+		deadline:  now.Add(expiration),
+		refreshAt: now.Add(expiration - time.Duration(float64(expiration)*r.ratio)),
+	}
+	// 不再访问的 key 不会触发清理, 定期扫一遍删掉已经过期的
+	r.tracks++
+	if r.tracks%1024 == 0 {
+		for k, e := range r.entries {
+			if !now.Before(e.deadline) {
+				delete(r.entries, k)
+			}
+		}
+	}
+}
+
+func (r *RefreshAheadCache) untrack(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entries, key)
+}
+
+// deadlineCache 写入和删除的时候同步更新 RefreshAheadCache 记录的过期时间
+type deadlineCache struct {
+	Cache
+	r *RefreshAheadCache
+}
+
+// Set 底层的 MapCache 配置了 ExpirationStrategy 的时候在这里提前调整, 记录的过期时间和缓存中的一致
+func (d *deadlineCache) Set(ctx context.Context, key string, val any, expiration time.Duration) error {
+	return d.setAdjusted(ctx, key, val, expirationOf(strategyOf(d.Cache), key, expiration))
+}
+
+func (d *deadlineCache) setAdjusted(ctx context.Context, key string, val any, expiration time.Duration) error {
+	err := setAdjusted(ctx, d.Cache, key, val, expiration)
+	if err == nil {
+		d.r.track(key, expiration)
+	}
+	return err
+}
+
+func (d *deadlineCache) Delete(ctx context.Context, key string) error {
+	d.r.untrack(key)
+	return d.Cache.Delete(ctx, key)
+}
+
+func (d *deadlineCache) LoadAndDelete(ctx context.Context, key string) (any, error) {
+	d.r.untrack(key)
+	return d.Cache.LoadAndDelete(ctx, key)
+}
+
+func (d *deadlineCache) unwrap() Cache {
+	return d.Cache
+}
@@ -0,0 +1,124 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/NotFound1911/gcache/clock/clocktest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRefreshAheadCache_Get(t *testing.T) {
+	clk := clocktest.NewFakeClock(time.Now())
+	local := NewMapCache(time.Minute, BuildMapCacheWithClock(clk))
+	defer local.Close()
+	var version atomic.Int32
+	release := make(chan struct{}, 1)
+	c := NewRefreshAheadCache(local, func(ctx context.Context, key string) (any, error) {
+		v := version.Add(1)
+		if v > 1 {
+			<-release
+		}
+		return fmt.Sprintf("v%d", v), nil
+	}, 10*time.Second, BuildRefreshAheadWithClock(clk))
+	ctx := context.Background()
+
+	val, err := c.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", val)
+
+	// 还没有到刷新的时间
+	clk.Advance(7 * time.Second)
+	val, err = c.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", val)
+	assert.Equal(t, int32(1), version.Load())
+
+	// 进入最后 20% 的时间, 后台刷新, 刷新完成之前返回旧的值
+	clk.Advance(2 * time.Second)
+	val, err = c.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", val)
+	require.Eventually(t, func() bool {
+		return version.Load() == 2
+	}, time.Second, time.Millisecond)
+	// 同一个 key 不会重复刷新
+	val, err = c.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", val)
+	assert.Equal(t, int32(2), version.Load())
+
+	release <- struct{}{}
+	require.Eventually(t, func() bool {
+		val, err := local.Get(ctx, "key1")
+		return err == nil && val == "v2"
+	}, time.Second, time.Millisecond)
+	// 刷新之后重新计算过期时间, 原来的过期时间过了也不会未命中
+	clk.Advance(5 * time.Second)
+	val, err = c.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", val)
+	assert.Equal(t, int64(2), c.Stats().LoadSuccesses)
+}
+
+func TestRefreshAheadCache_Concurrency(t *testing.T) {
+	clk := clocktest.NewFakeClock(time.Now())
+	local := NewMapCache(time.Minute, BuildMapCacheWithClock(clk))
+	defer local.Close()
+	ctx := context.Background()
+	var loads, running atomic.Int32
+	release := make(chan struct{})
+	c := NewRefreshAheadCache(local, func(ctx context.Context, key string) (any, error) {
+		loads.Add(1)
+		return "val", nil
+	}, 10*time.Second, BuildRefreshAheadWithClock(clk), BuildRefreshAheadWithConcurrency(2))
+	for i := 0; i < 5; i++ {
+		_, err := c.Get(ctx, fmt.Sprintf("key_%d", i))
+		require.NoError(t, err)
+	}
+	c.LoadFunc = func(ctx context.Context, key string) (any, error) {
+		running.Add(1)
+		<-release
+		return nil, errors.New("db error")
+	}
+	clk.Advance(9 * time.Second)
+	for i := 0; i < 5; i++ {
+		val, err := c.Get(ctx, fmt.Sprintf("key_%d", i))
+		require.NoError(t, err)
+		assert.Equal(t, "val", val)
+	}
+	require.Eventually(t, func() bool {
+		return running.Load() == 2
+	}, time.Second, time.Millisecond)
+	close(release)
+	// 刷新失败继续使用旧的值
+	require.Eventually(t, func() bool {
+		return c.Stats().LoadFailures == 2
+	}, time.Second, time.Millisecond)
+	val, err := c.Get(ctx, "key_0")
+	require.NoError(t, err)
+	assert.Equal(t, "val", val)
+	assert.Equal(t, int32(5), loads.Load())
+}
+
+func TestRefreshAheadCache_Delete(t *testing.T) {
+	local := NewMapCache(time.Minute)
+	defer local.Close()
+	ctx := context.Background()
+	c := NewRefreshAheadCache(local, func(ctx context.Context, key string) (any, error) {
+		return "val", nil
+	}, time.Minute)
+	require.NoError(t, c.Set(ctx, "key1", "val", time.Minute))
+	require.NoError(t, c.Set(ctx, "forever", "val", 0))
+	c.mu.Lock()
+	assert.Len(t, c.entries, 1)
+	c.mu.Unlock()
+	require.NoError(t, c.Delete(ctx, "key1"))
+	c.mu.Lock()
+	assert.Len(t, c.entries, 0)
+	c.mu.Unlock()
+}
@@ -0,0 +1,53 @@
+package gcache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// refresher 在后台重新加载 key, 同一个 key 同时只有一个刷新, 并且限制总的并发数量
+type refresher struct {
+	sem     chan struct{}
+	timeout time.Duration
+
+	mu      sync.Mutex
+	running map[string]struct{}
+}
+
+func newRefresher() *refresher {
+	return &refresher{
+		sem:     make(chan struct{}, 10),
+		timeout: time.Second * 10,
+		running: make(map[string]struct{}),
+	}
+}
+
+// refresh 并发数量已满或者 key 正在刷新的时候直接放弃
+func (r *refresher) refresh(key string, rt *ReadTroughCache) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.running[key]; ok {
+		return
+	}
+	select {
+	case r.sem <- struct{}{}:
+	default:
+		return
+	}
+	r.running[key] = struct{}{}
+	go func() {
+		defer func() {
+			r.mu.Lock()
+			delete(r.running, key)
+			r.mu.Unlock()
+			<-r.sem
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+		defer cancel()
+		// 和同步加载共用 singleflight, 刷新期间的未命中不会重复加载
+		_, _, _ = rt.g.Do(key, func() (any, error) {
+			return rt.load(ctx, key)
+		})
+	}()
+}
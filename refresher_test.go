@@ -0,0 +1,50 @@
+package gcache
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRefresher_Refresh(t *testing.T) {
+	local := NewMapCache(time.Minute)
+	defer local.Close()
+	var loads atomic.Int64
+	release := make(chan struct{})
+	rt := &ReadTroughCache{
+		Cache: local,
+		LoadFunc: func(ctx context.Context, key string) (any, error) {
+			loads.Add(1)
+			<-release
+			return "val_" + key, nil
+		},
+		Expiration: time.Minute,
+	}
+	r := newRefresher()
+	r.sem = make(chan struct{}, 2)
+
+	// 同一个 key 正在刷新的时候不会重复刷新, 超过并发数量的 key 直接放弃
+	r.refresh("key1", rt)
+	r.refresh("key1", rt)
+	r.refresh("key2", rt)
+	r.refresh("key3", rt)
+	require.Eventually(t, func() bool {
+		return loads.Load() == 2
+	}, time.Second, time.Millisecond)
+	close(release)
+	require.Eventually(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return len(r.running) == 0
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, int64(2), loads.Load())
+	val, err := local.Get(context.Background(), "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "val_key1", val)
+	_, err = local.Get(context.Background(), "key3")
+	assert.True(t, IsKeyNotFound(err))
+}
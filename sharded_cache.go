@@ -0,0 +1,106 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ShardedMapCache 按照 key 的哈希值把数据分散到多个 MapCache 中
+// 每个分片有自己的锁和过期清理协程, 减少锁竞争
+type ShardedMapCache struct {
+	shards []*MapCache
+}
+
+// NewShardedMapCache opts 会作用于每一个分片, 所以 maxCnt 之类的配置是针对单个分片的
+// 每个分片需要独立的淘汰策略, 自定义的淘汰策略使用 BuildMapCacheWithEvictionPolicyFactory,
+// 多个分片使用 BuildMapCacheWithEvictionPolicy 传入的同一个实例会返回 ErrSharedEvictionPolicy.
+// 淘汰回调是所有分片共享的, 会在不同分片的锁内被并发调用, 回调需要自己保证并发安全
+func NewShardedMapCache(shardCnt int, interval time.Duration, opts ...MapCacheOption) (*ShardedMapCache, error) {
+	if shardCnt <= 0 {
+		shardCnt = 1
+	}
+	shards := make([]*MapCache, shardCnt)
+	for i := range shards {
+		shards[i] = NewMapCache(interval, opts...)
+	}
+	if shardCnt > 1 && shards[0].sharedPolicy {
+		// 淘汰策略只受单个分片的锁保护, 分片之间共享会产生数据竞争
+		for _, shard := range shards {
+			_ = shard.Close()
+		}
+		return nil, ErrSharedEvictionPolicy
+	}
+	return &ShardedMapCache{
+		shards: shards,
+	}, nil
+}
+
+func (s *ShardedMapCache) shard(key string) *MapCache {
+	// 内联的 FNV-1a, 避免 hash.Hash32 带来的内存分配
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return s.shards[h%uint32(len(s.shards))]
+}
+
+func (s *ShardedMapCache) Set(ctx context.Context, key string, val any, expiration time.Duration) error {
+	return s.shard(key).Set(ctx, key, val, expiration)
+}
+
+func (s *ShardedMapCache) setAdjusted(ctx context.Context, key string, val any, expiration time.Duration) error {
+	return setAdjusted(ctx, s.shard(key), key, val, expiration)
+}
+
+func (s *ShardedMapCache) Get(ctx context.Context, key string) (any, error) {
+	return s.shard(key).Get(ctx, key)
+}
+
+func (s *ShardedMapCache) GetWithTTL(ctx context.Context, key string) (any, time.Duration, error) {
+	return s.shard(key).GetWithTTL(ctx, key)
+}
+
+func (s *ShardedMapCache) Delete(ctx context.Context, key string) error {
+	return s.shard(key).Delete(ctx, key)
+}
+
+func (s *ShardedMapCache) LoadAndDelete(ctx context.Context, key string) (any, error) {
+	return s.shard(key).LoadAndDelete(ctx, key)
+}
+
+// Stats 汇总所有分片的统计数据
+func (s *ShardedMapCache) Stats() Stats {
+	res := Stats{
+		Evictions: make(map[EvictionReason]int64),
+	}
+	for _, shard := range s.shards {
+		st := shard.Stats()
+		res.Hits += st.Hits
+		res.Misses += st.Misses
+		res.Sets += st.Sets
+		res.Deletes += st.Deletes
+		res.Entries += st.Entries
+		for reason, cnt := range st.Evictions {
+			res.Evictions[reason] += cnt
+		}
+	}
+	return res
+}
+
+func (s *ShardedMapCache) ResetStats() {
+	for _, shard := range s.shards {
+		shard.ResetStats()
+	}
+}
+
+func (s *ShardedMapCache) Close() error {
+	var errs []error
+	for _, shard := range s.shards {
+		if err := shard.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,154 @@
+package gcache
+
+import (
+	"context"
+	"fmt"
+	"github.com/NotFound1911/gcache/clock/clocktest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestShardedMapCache(t *testing.T) {
+	c, err := NewShardedMapCache(8, time.Minute)
+	require.NoError(t, err)
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		require.NoError(t, c.Set(ctx, fmt.Sprintf("key_%d", i), i, time.Minute))
+	}
+	used := 0
+	for _, shard := range c.shards {
+		if len(shard.data) > 0 {
+			used++
+		}
+	}
+	assert.Greater(t, used, 1)
+
+	val, err := c.Get(ctx, "key_10")
+	require.NoError(t, err)
+	assert.Equal(t, 10, val)
+
+	val, err = c.LoadAndDelete(ctx, "key_10")
+	require.NoError(t, err)
+	assert.Equal(t, 10, val)
+	_, err = c.Get(ctx, "key_10")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	require.NoError(t, c.Delete(ctx, "key_11"))
+	_, err = c.Get(ctx, "key_11")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestShardedMapCache_Loop(t *testing.T) {
+	cnt := 0
+	clk := clocktest.NewFakeClock(time.Now())
+	c, err := NewShardedMapCache(1, time.Second, BuildMapCacheWithClock(clk),
+		BuildMapCacheWithEvictedCallback(func(key string, val any) {
+			cnt++
+		}))
+	require.NoError(t, err)
+	err = c.Set(context.Background(), "key", 456, time.Second)
+	require.NoError(t, err)
+	clk.Advance(time.Second * 2)
+	shard := c.shard("key")
+	eventually(t, shard, func() bool {
+		_, ok := shard.data["key"]
+		return !ok && cnt == 1
+	})
+}
+
+func TestShardedMapCache_Policy(t *testing.T) {
+	testCases := []struct {
+		name     string
+		shardCnt int
+		opts     []MapCacheOption
+		wantErr  error
+	}{
+		{
+			// 每个分片有自己的淘汰策略
+			name:     "factory",
+			shardCnt: 4,
+			opts:     []MapCacheOption{BuildMapCacheWithEvictionPolicyFactory(NewLRUPolicy)},
+		},
+		{
+			name:     "lru",
+			shardCnt: 4,
+			opts:     []MapCacheOption{BuildMapCacheWithLRU()},
+		},
+		{
+			name:     "shared policy",
+			shardCnt: 4,
+			opts:     []MapCacheOption{BuildMapCacheWithEvictionPolicy(NewLRUPolicy())},
+			wantErr:  ErrSharedEvictionPolicy,
+		},
+		{
+			// 后面的配置覆盖了共享的淘汰策略
+			name:     "overridden",
+			shardCnt: 4,
+			opts:     []MapCacheOption{BuildMapCacheWithEvictionPolicy(NewLRUPolicy()), BuildMapCacheWithLRU()},
+		},
+		{
+			name:     "nil policy",
+			shardCnt: 4,
+			opts:     []MapCacheOption{BuildMapCacheWithEvictionPolicy(nil)},
+		},
+		{
+			// 只有一个分片的时候可以直接使用
+			name:     "single shard",
+			shardCnt: 1,
+			opts:     []MapCacheOption{BuildMapCacheWithEvictionPolicy(NewLRUPolicy())},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewShardedMapCache(tc.shardCnt, time.Minute, tc.opts...)
+			assert.ErrorIs(t, err, tc.wantErr)
+			if err != nil {
+				return
+			}
+			defer c.Close()
+			for i := 1; i < len(c.shards); i++ {
+				assert.NotSame(t, c.shards[0].policy, c.shards[i].policy)
+			}
+		})
+	}
+}
+
+const benchKeyCnt = 10000
+
+func benchmarkCacheParallel(b *testing.B, c Cache) {
+	ctx := context.Background()
+	keys := make([]string, benchKeyCnt)
+	for i := range keys {
+		keys[i] = "key_" + strconv.Itoa(i)
+		_ = c.Set(ctx, keys[i], i, time.Minute)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i%benchKeyCnt]
+			// 读多写少
+			if i%10 == 0 {
+				_ = c.Set(ctx, key, i, time.Minute)
+			} else {
+				_, _ = c.Get(ctx, key)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkMapCache_Parallel(b *testing.B) {
+	benchmarkCacheParallel(b, NewMapCache(time.Minute))
+}
+
+func BenchmarkShardedMapCache_Parallel(b *testing.B) {
+	c, err := NewShardedMapCache(runtime.GOMAXPROCS(0)*4, time.Minute)
+	require.NoError(b, err)
+	benchmarkCacheParallel(b, c)
+}
@@ -0,0 +1,75 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type StaleWhileRevalidateOption func(s *StaleWhileRevalidateCache)
+
+// StaleWhileRevalidateCache 软过期和硬过期
+// 超过软过期时间的值依旧会立刻返回, 同时在后台重新加载;
+// 重新加载失败的话继续返回旧的值, 直到硬过期之后缓存中的数据被清理
+type StaleWhileRevalidateCache struct {
+	*ReadTroughCache
+	cache      TTLCache
+	staleAfter time.Duration // 剩余过期时间不超过 staleAfter 的数据已经软过期
+	refresher  *refresher
+}
+
+// NewStaleWhileRevalidateCache softExpiration 是软过期时间, hardExpiration 是写入缓存的过期时间
+// 缓存中保存的是原始的值, 软过期通过 GetWithTTL 返回的剩余过期时间判断:
+// 剩余过期时间不超过 hardExpiration - softExpiration 的数据已经软过期.
+// ExpirationStrategy 调整的是硬过期时间, 软过期时间会跟着一起提前或者推后
+func NewStaleWhileRevalidateCache(c TTLCache, loadFunc func(ctx context.Context, key string) (any, error),
+	softExpiration, hardExpiration time.Duration, opts ...StaleWhileRevalidateOption) *StaleWhileRevalidateCache {
+	res := &StaleWhileRevalidateCache{
+		ReadTroughCache: &ReadTroughCache{
+			Cache:      c,
+			LoadFunc:   loadFunc,
+			Expiration: hardExpiration,
+		},
+		cache:      c,
+		staleAfter: hardExpiration - softExpiration,
+		refresher:  newRefresher(),
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// BuildStaleWhileRevalidateWithConcurrency 同时进行的后台刷新数量, 默认 10
+func BuildStaleWhileRevalidateWithConcurrency(concurrency int) StaleWhileRevalidateOption {
+	return func(s *StaleWhileRevalidateCache) {
+		s.refresher.sem = make(chan struct{}, concurrency)
+	}
+}
+
+// BuildStaleWhileRevalidateWithTimeout 后台刷新的超时时间, 默认 10 秒
+func BuildStaleWhileRevalidateWithTimeout(timeout time.Duration) StaleWhileRevalidateOption {
+	return func(s *StaleWhileRevalidateCache) {
+		s.refresher.timeout = timeout
+	}
+}
+
+func (s *StaleWhileRevalidateCache) Get(ctx context.Context, key string) (any, error) {
+	val, ttl, err := s.cache.GetWithTTL(ctx, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		// 硬过期之后只能同步加载
+		return s.sharedLoad(ctx, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if isTombstone(val) {
+		// 不存在的数据使用 NegativeExpiration, 过期之后再重新加载
+		return nil, fmt.Errorf("%w, key: %s", ErrKeyNotFound, key)
+	}
+	if ttl != NoExpiration && ttl <= s.staleAfter {
+		s.refresher.refresh(key, s.ReadTroughCache)
+	}
+	return val, nil
+}
@@ -0,0 +1,117 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/NotFound1911/gcache/clock/clocktest"
+	"github.com/NotFound1911/gcache/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStaleWhileRevalidateCache_Get(t *testing.T) {
+	clk := clocktest.NewFakeClock(time.Now())
+	local := NewMapCache(time.Hour, BuildMapCacheWithClock(clk))
+	defer local.Close()
+	var version atomic.Int32
+	var fail atomic.Bool
+	c := NewStaleWhileRevalidateCache(local, func(ctx context.Context, key string) (any, error) {
+		if fail.Load() {
+			return nil, errors.New("db down")
+		}
+		return fmt.Sprintf("v%d", version.Add(1)), nil
+	}, 10*time.Second, time.Minute)
+	ctx := context.Background()
+
+	val, err := c.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", val)
+	// 未命中之后直接加载, 不会再读一次缓存
+	assert.Equal(t, int64(1), c.Stats().Misses)
+
+	// 软过期之后立刻返回旧的值, 后台重新加载
+	clk.Advance(11 * time.Second)
+	val, err = c.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", val)
+	require.Eventually(t, func() bool {
+		val, err := c.Get(ctx, "key1")
+		return err == nil && val == "v2"
+	}, time.Second, time.Millisecond)
+
+	// 数据库不可用时继续返回旧的值, 直到硬过期
+	fail.Store(true)
+	clk.Advance(30 * time.Second)
+	for i := 0; i < 3; i++ {
+		val, err = c.Get(ctx, "key1")
+		require.NoError(t, err)
+		assert.Equal(t, "v2", val)
+	}
+	require.Eventually(t, func() bool {
+		return c.Stats().LoadFailures > 0
+	}, time.Second, time.Millisecond)
+	val, err = c.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", val)
+
+	clk.Advance(31 * time.Second)
+	_, err = c.Get(ctx, "key1")
+	assert.EqualError(t, err, "db down")
+}
+
+func TestStaleWhileRevalidateCache_Tombstone(t *testing.T) {
+	clk := clocktest.NewFakeClock(time.Now())
+	local := NewMapCache(time.Hour, BuildMapCacheWithClock(clk))
+	defer local.Close()
+	var loads atomic.Int32
+	c := NewStaleWhileRevalidateCache(local, func(ctx context.Context, key string) (any, error) {
+		loads.Add(1)
+		return nil, ErrNotExist
+	}, 10*time.Second, time.Minute)
+	c.NegativeExpiration = time.Second
+	ctx := context.Background()
+
+	// 不存在的数据不会在后台重新加载
+	for i := 0; i < 3; i++ {
+		_, err := c.Get(ctx, "key1")
+		assert.True(t, IsKeyNotFound(err))
+	}
+	assert.Equal(t, int32(1), loads.Load())
+	clk.Advance(2 * time.Second)
+	_, err := c.Get(ctx, "key1")
+	assert.True(t, IsKeyNotFound(err))
+	assert.Equal(t, int32(2), loads.Load())
+}
+
+func TestStaleWhileRevalidateCache_Redis(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := mocks.NewMockCmdable(ctrl)
+	// 剩余 40 秒, 已经超过了 10 秒的软过期时间
+	hit := redis.NewCmd(context.Background())
+	hit.SetVal([]any{"old", int64(40000)})
+	cmd.EXPECT().Eval(gomock.Any(), luaGetWithTTL, []string{"key1"}).Return(hit)
+	stored := make(chan any, 1)
+	cmd.EXPECT().Set(gomock.Any(), "key1", gomock.Any(), time.Minute).
+		DoAndReturn(func(ctx context.Context, key string, val any, expiration time.Duration) *redis.StatusCmd {
+			stored <- val
+			status := redis.NewStatusCmd(context.Background())
+			status.SetVal("OK")
+			return status
+		})
+	c := NewStaleWhileRevalidateCache(NewRedisCache(cmd), func(ctx context.Context, key string) (any, error) {
+		return "new", nil
+	}, 10*time.Second, time.Minute)
+
+	val, err := c.Get(context.Background(), "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "old", val)
+	// 写入 redis 的是原始的值
+	assert.Equal(t, "new", <-stored)
+}
@@ -0,0 +1,135 @@
+package gcache
+
+import "sync/atomic"
+
+// Stats 缓存统计数据的快照
+type Stats struct {
+	Hits    int64
+	Misses  int64
+	Sets    int64
+	Deletes int64
+	// Evictions 按照原因统计的移出次数
+	Evictions     map[EvictionReason]int64
+	LoadSuccesses int64
+	LoadFailures  int64
+	// LoadsDeduplicated 因为同一个 key 已经在加载而没有重复加载的次数
+	LoadsDeduplicated int64
+	// Entries 当前缓存的数据量, 无法统计的实现返回 0
+	Entries int64
+}
+
+// HitRatio 命中率, 没有任何查询时返回 0
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// StatsProvider 提供统计数据的缓存
+type StatsProvider interface {
+	// Stats 返回当前统计数据的快照
+	Stats() Stats
+	// ResetStats 清空统计数据, 不影响 Entries
+	ResetStats()
+}
+
+// statsCounter 使用原子操作计数, 零值可以直接使用
+type statsCounter struct {
+	hits          atomic.Int64
+	misses        atomic.Int64
+	sets          atomic.Int64
+	deletes       atomic.Int64
+	evictions     [EvictionClosed + 1]atomic.Int64
+	loadSuccesses atomic.Int64
+	loadFailures  atomic.Int64
+
+	loadsDeduplicated atomic.Int64
+}
+
+func (s *statsCounter) evicted(reason EvictionReason) {
+	if int(reason) < len(s.evictions) {
+		s.evictions[reason].Add(1)
+	}
+}
+
+func (s *statsCounter) loaded(err error) {
+	if err != nil {
+		s.loadFailures.Add(1)
+		return
+	}
+	s.loadSuccesses.Add(1)
+}
+
+func (s *statsCounter) snapshot() Stats {
+	res := Stats{
+		Hits:          s.hits.Load(),
+		Misses:        s.misses.Load(),
+		Sets:          s.sets.Load(),
+		Deletes:       s.deletes.Load(),
+		Evictions:     make(map[EvictionReason]int64, len(s.evictions)),
+		LoadSuccesses: s.loadSuccesses.Load(),
+		LoadFailures:  s.loadFailures.Load(),
+
+		LoadsDeduplicated: s.loadsDeduplicated.Load(),
+	}
+	for i := range s.evictions {
+		if cnt := s.evictions[i].Load(); cnt > 0 {
+			res.Evictions[EvictionReason(i)] = cnt
+		}
+	}
+	return res
+}
+
+func (s *statsCounter) reset() {
+	s.hits.Store(0)
+	s.misses.Store(0)
+	s.sets.Store(0)
+	s.deletes.Store(0)
+	for i := range s.evictions {
+		s.evictions[i].Store(0)
+	}
+	s.loadSuccesses.Store(0)
+	s.loadFailures.Store(0)
+	s.loadsDeduplicated.Store(0)
+}
+
+// wrapper 装饰器放在加载逻辑和底层缓存之间的包装, 例如 deadlineCache 和 envelopeCache
+// 包装本身没有统计数据, 统计数据从被包装的缓存中获取
+type wrapper interface {
+	unwrap() Cache
+}
+
+// statsProviderOf 跳过所有的 wrapper, 找到底层缓存的统计数据
+func statsProviderOf(c Cache) (StatsProvider, bool) {
+	for {
+		w, ok := c.(wrapper)
+		if !ok {
+			break
+		}
+		c = w.unwrap()
+	}
+	sp, ok := c.(StatsProvider)
+	return sp, ok
+}
+
+// loaderStats 读穿透装饰器的统计数据, 在底层缓存的基础上加上加载的次数
+func loaderStats(c Cache, s *statsCounter) Stats {
+	res := s.snapshot()
+	if sp, ok := statsProviderOf(c); ok {
+		inner := sp.Stats()
+		inner.LoadSuccesses = res.LoadSuccesses
+		inner.LoadFailures = res.LoadFailures
+		inner.LoadsDeduplicated = res.LoadsDeduplicated
+		return inner
+	}
+	return res
+}
+
+func resetLoaderStats(c Cache, s *statsCounter) {
+	s.reset()
+	if sp, ok := statsProviderOf(c); ok {
+		sp.ResetStats()
+	}
+}
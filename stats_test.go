@@ -0,0 +1,111 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"github.com/NotFound1911/gcache/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestMapCache_Stats(t *testing.T) {
+	c := NewMaxCntCache(NewMapCache(time.Minute, BuildMapCacheWithLRU()), 2)
+	defer c.Close()
+	ctx := context.Background()
+	require.NoError(t, c.Set(ctx, "key1", 1, time.Minute))
+	require.NoError(t, c.Set(ctx, "key1", 2, time.Minute))
+	require.NoError(t, c.Set(ctx, "key2", 2, time.Minute))
+	require.NoError(t, c.Set(ctx, "key3", 3, time.Minute))
+	_, err := c.Get(ctx, "key2")
+	require.NoError(t, err)
+	_, err = c.Get(ctx, "key1")
+	require.Error(t, err)
+	require.NoError(t, c.Delete(ctx, "key2"))
+
+	assert.Equal(t, Stats{
+		Hits:    1,
+		Misses:  1,
+		Sets:    4,
+		Deletes: 1,
+		Evictions: map[EvictionReason]int64{
+			EvictionReplaced: 1,
+			EvictionCapacity: 1,
+			EvictionDeleted:  1,
+		},
+		Entries: 1,
+	}, c.Stats())
+	assert.Equal(t, 0.5, c.Stats().HitRatio())
+
+	c.ResetStats()
+	assert.Equal(t, Stats{
+		Evictions: map[EvictionReason]int64{},
+		Entries:   1,
+	}, c.Stats())
+}
+
+func TestRedisCache_Stats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := mocks.NewMockCmdable(ctrl)
+	hit := redis.NewStringCmd(context.Background())
+	hit.SetVal("val")
+	miss := redis.NewStringCmd(context.Background())
+	miss.SetErr(redis.Nil)
+	timeout := redis.NewStringCmd(context.Background())
+	timeout.SetErr(context.DeadlineExceeded)
+	gomock.InOrder(
+		cmd.EXPECT().Get(context.Background(), "key1").Return(hit),
+		cmd.EXPECT().Get(context.Background(), "key2").Return(miss),
+		cmd.EXPECT().Get(context.Background(), "key3").Return(timeout),
+	)
+	c := NewRedisCache(cmd)
+	_, _ = c.Get(context.Background(), "key1")
+	_, _ = c.Get(context.Background(), "key2")
+	_, _ = c.Get(context.Background(), "key3")
+	assert.Equal(t, Stats{
+		Hits:      1,
+		Misses:    1,
+		Evictions: map[EvictionReason]int64{},
+	}, c.Stats())
+}
+
+// missCache 总是返回未找到, 用来触发加载
+type missCache struct {
+	*MapCache
+}
+
+func (m *missCache) Get(ctx context.Context, key string) (any, error) {
+	m.stats.misses.Add(1)
+	return nil, ErrKeyNotFound
+}
+
+func TestReadTroughCache_Stats(t *testing.T) {
+	c := &ReadTroughCache{
+		Cache: &missCache{MapCache: NewMapCache(time.Minute)},
+		LoadFunc: func(ctx context.Context, key string) (any, error) {
+			if key == "invalid" {
+				return nil, errors.New("mock db error")
+			}
+			return key, nil
+		},
+		Expiration: time.Minute,
+	}
+	_, err := c.Get(context.Background(), "key1")
+	require.NoError(t, err)
+	_, err = c.Get(context.Background(), "invalid")
+	require.Error(t, err)
+	assert.Equal(t, Stats{
+		Misses:        2,
+		Sets:          1,
+		Evictions:     map[EvictionReason]int64{},
+		LoadSuccesses: 1,
+		LoadFailures:  1,
+		Entries:       1,
+	}, c.Stats())
+	c.ResetStats()
+	assert.Equal(t, int64(0), c.Stats().LoadSuccesses)
+}
@@ -0,0 +1,244 @@
+package gcache
+
+import (
+	"container/list"
+	"hash/fnv"
+	"sync"
+)
+
+const (
+	cmDepth   = 4
+	cmMaxFreq = 15
+)
+
+// cmSketch count-min sketch, 用来估算 key 的访问频率
+// 计数器达到上限后不再增加, 访问总数达到阈值之后所有计数器减半, 让旧的热点慢慢冷却
+type cmSketch struct {
+	rows      [cmDepth][]uint8
+	mask      uint64
+	additions int
+	resetAt   int
+}
+
+func newCMSketch(capacity int) *cmSketch {
+	width := 16
+	for width < capacity {
+		width <<= 1
+	}
+	s := &cmSketch{
+		mask:    uint64(width - 1),
+		resetAt: width * 10,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// indexes 使用双重哈希计算每一行的下标
+func (s *cmSketch) indexes(key string) [cmDepth]uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	sum := h.Sum64()
+	h1, h2 := sum&0xffffffff, sum>>32
+	var res [cmDepth]uint64
+	for i := range res {
+		res[i] = (h1 + uint64(i)*h2) & s.mask
+	}
+	return res
+}
+
+func (s *cmSketch) Increment(key string) {
+	for i, idx := range s.indexes(key) {
+		if s.rows[i][idx] < cmMaxFreq {
+			s.rows[i][idx]++
+		}
+	}
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.reset()
+	}
+}
+
+func (s *cmSketch) Estimate(key string) uint8 {
+	res := uint8(cmMaxFreq)
+	for i, idx := range s.indexes(key) {
+		if s.rows[i][idx] < res {
+			res = s.rows[i][idx]
+		}
+	}
+	return res
+}
+
+func (s *cmSketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions >>= 1
+}
+
+type tinyLFUSegment uint8
+
+const (
+	segmentWindow tinyLFUSegment = iota
+	segmentProbation
+	segmentProtected
+)
+
+type tinyLFUEntry struct {
+	segment tinyLFUSegment
+	elem    *list.Element
+}
+
+// wTinyLFUPolicy W-TinyLFU 淘汰策略
+// 新写入的 key 先进入窗口区(LRU), 窗口区淘汰出来的 key 需要和主区(SLRU)的淘汰候选比较频率,
+// 频率更高的才能进入主区, 这样一次性的扫描不会把热点数据挤出去
+type wTinyLFUPolicy struct {
+	mu           sync.Mutex
+	sketch       *cmSketch
+	window       *list.List
+	probation    *list.List
+	protected    *list.List
+	entries      map[string]*tinyLFUEntry
+	windowCap    int
+	mainCap      int
+	protectedCap int
+}
+
+func newWTinyLFUPolicy(capacity int) *wTinyLFUPolicy {
+	windowCap := capacity / 100
+	if windowCap < 1 {
+		windowCap = 1
+	}
+	mainCap := capacity - windowCap
+	return &wTinyLFUPolicy{
+		sketch:       newCMSketch(capacity),
+		window:       list.New(),
+		probation:    list.New(),
+		protected:    list.New(),
+		entries:      make(map[string]*tinyLFUEntry, 128),
+		windowCap:    windowCap,
+		mainCap:      mainCap,
+		protectedCap: mainCap * 8 / 10,
+	}
+}
+
+func (w *wTinyLFUPolicy) OnSet(key string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.sketch.Increment(key)
+	if entry, ok := w.entries[key]; ok {
+		w.onAccess(entry)
+		return
+	}
+	w.entries[key] = &tinyLFUEntry{
+		segment: segmentWindow,
+		elem:    w.window.PushFront(key),
+	}
+	// 主区还没满的时候, 窗口区多出来的 key 直接进入主区
+	for w.window.Len() > w.windowCap && w.probation.Len()+w.protected.Len() < w.mainCap {
+		w.move(w.window.Back(), segmentProbation)
+	}
+}
+
+func (w *wTinyLFUPolicy) OnGet(key string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.sketch.Increment(key)
+	if entry, ok := w.entries[key]; ok {
+		w.onAccess(entry)
+	}
+}
+
+func (w *wTinyLFUPolicy) OnDelete(key string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	entry, ok := w.entries[key]
+	if !ok {
+		return
+	}
+	w.segment(entry.segment).Remove(entry.elem)
+	delete(w.entries, key)
+}
+
+func (w *wTinyLFUPolicy) Victim() (string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var candidate, victim *list.Element
+	// 新的 key 会进入窗口区, 窗口区已满则需要挪出一个候选者
+	if w.window.Len() >= w.windowCap {
+		candidate = w.window.Back()
+	}
+	if victim = w.probation.Back(); victim == nil {
+		victim = w.protected.Back()
+	}
+	switch {
+	case candidate == nil && victim == nil:
+		if back := w.window.Back(); back != nil {
+			return back.Value.(string), true
+		}
+		return "", false
+	case candidate == nil:
+		return victim.Value.(string), true
+	case victim == nil:
+		return candidate.Value.(string), true
+	}
+	candidateKey, victimKey := candidate.Value.(string), victim.Value.(string)
+	if w.sketch.Estimate(candidateKey) > w.sketch.Estimate(victimKey) {
+		// 候选者胜出, 进入主区
+		w.move(candidate, segmentProbation)
+		return victimKey, true
+	}
+	return candidateKey, true
+}
+
+func (w *wTinyLFUPolicy) onAccess(entry *tinyLFUEntry) {
+	switch entry.segment {
+	case segmentWindow:
+		w.window.MoveToFront(entry.elem)
+	case segmentProbation:
+		w.move(entry.elem, segmentProtected)
+		// 保护区超出容量, 把最久没有访问的降级回考察区
+		if w.protected.Len() > w.protectedCap {
+			w.move(w.protected.Back(), segmentProbation)
+		}
+	case segmentProtected:
+		w.protected.MoveToFront(entry.elem)
+	}
+}
+
+// move 把 elem 挪到 segment 的头部
+func (w *wTinyLFUPolicy) move(elem *list.Element, segment tinyLFUSegment) {
+	key := elem.Value.(string)
+	entry := w.entries[key]
+	w.segment(entry.segment).Remove(elem)
+	entry.segment = segment
+	entry.elem = w.segment(segment).PushFront(key)
+}
+
+func (w *wTinyLFUPolicy) segment(segment tinyLFUSegment) *list.List {
+	switch segment {
+	case segmentProbation:
+		return w.probation
+	case segmentProtected:
+		return w.protected
+	default:
+		return w.window
+	}
+}
+
+// NewWTinyLFUPolicy 创建 W-TinyLFU 淘汰策略, capacity 需要和 MaxCntCache 的容量保持一致
+func NewWTinyLFUPolicy(capacity int) EvictionPolicy {
+	return newWTinyLFUPolicy(capacity)
+}
+
+// BuildMapCacheWithWTinyLFU 使用 W-TinyLFU 淘汰策略
+// capacity 需要和 MaxCntCache 的容量保持一致, 用来划分窗口区和主区的大小
+func BuildMapCacheWithWTinyLFU(capacity int) MapCacheOption {
+	return func(cache *MapCache) {
+		cache.policy = newWTinyLFUPolicy(capacity)
+		cache.sharedPolicy = false
+	}
+}
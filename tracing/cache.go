@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"context"
+	"github.com/NotFound1911/gcache"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+// Cache 为被装饰的缓存的每个操作创建 span, 未命中不会被标记为错误
+type Cache struct {
+	gcache.Cache
+	name   string
+	tracer *Tracer
+}
+
+var _ gcache.Cache = (*Cache)(nil)
+
+func (t *Tracer) WrapCache(name string, cache gcache.Cache) *Cache {
+	return &Cache{
+		Cache:  cache,
+		name:   name,
+		tracer: t,
+	}
+}
+
+func (c *Cache) start(ctx context.Context, op string, key string) (context.Context, trace.Span) {
+	return c.tracer.tracer.Start(ctx, "gcache."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrName.String(c.name), c.tracer.keyAttr(key)))
+}
+
+func (c *Cache) Set(ctx context.Context, key string, val any, expiration time.Duration) error {
+	ctx, span := c.start(ctx, "Set", key)
+	err := c.Cache.Set(ctx, key, val, expiration)
+	end(span, err, nil)
+	return err
+}
+
+func (c *Cache) Get(ctx context.Context, key string) (any, error) {
+	ctx, span := c.start(ctx, "Get", key)
+	val, err := c.Cache.Get(ctx, key)
+	span.SetAttributes(attrHit.Bool(err == nil))
+	end(span, err, gcache.IsKeyNotFound)
+	return val, err
+}
+
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	ctx, span := c.start(ctx, "Delete", key)
+	err := c.Cache.Delete(ctx, key)
+	end(span, err, nil)
+	return err
+}
+
+func (c *Cache) LoadAndDelete(ctx context.Context, key string) (any, error) {
+	ctx, span := c.start(ctx, "LoadAndDelete", key)
+	val, err := c.Cache.LoadAndDelete(ctx, key)
+	span.SetAttributes(attrHit.Bool(err == nil))
+	end(span, err, gcache.IsKeyNotFound)
+	return val, err
+}
+
+// WrapLoadFunc 为每次加载创建 span, 用来装饰 ReadTroughCache 的 LoadFunc
+func (t *Tracer) WrapLoadFunc(name string,
+	fn func(ctx context.Context, key string) (any, error)) func(ctx context.Context, key string) (any, error) {
+	return func(ctx context.Context, key string) (any, error) {
+		ctx, span := t.tracer.Start(ctx, "gcache.Load",
+			trace.WithAttributes(attrName.String(name), t.keyAttr(key)))
+		val, err := fn(ctx, key)
+		end(span, err, nil)
+		return val, err
+	}
+}
@@ -0,0 +1,108 @@
+package tracing
+
+import (
+	"context"
+	"github.com/NotFound1911/gcache"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+// LockClient 为加锁创建 span, 每次重试都会在 span 上记录一个事件
+type LockClient struct {
+	client *gcache.Client
+	name   string
+	tracer *Tracer
+}
+
+func (t *Tracer) WrapClient(name string, client *gcache.Client) *LockClient {
+	return &LockClient{
+		client: client,
+		name:   name,
+		tracer: t,
+	}
+}
+
+func (l *LockClient) start(ctx context.Context, op string, key string) (context.Context, trace.Span) {
+	return l.tracer.tracer.Start(ctx, "gcache.lock."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrName.String(l.name), l.tracer.keyAttr(key)))
+}
+
+func (l *LockClient) Lock(ctx context.Context, key string, expiration time.Duration,
+	timeout time.Duration, retry gcache.RetryStrategy) (*Lock, error) {
+	ctx, span := l.start(ctx, "Lock", key)
+	r := &tracingRetry{RetryStrategy: retry, span: span}
+	lock, err := l.client.Lock(ctx, key, expiration, timeout, r)
+	span.SetAttributes(attrRetries.Int(r.cnt))
+	if err == nil {
+		span.AddEvent("attempt succeeded")
+	}
+	end(span, err, nil)
+	return l.wrap(lock, key), err
+}
+
+func (l *LockClient) TryLock(ctx context.Context, key string, expiration time.Duration) (*Lock, error) {
+	ctx, span := l.start(ctx, "TryLock", key)
+	lock, err := l.client.TryLock(ctx, key, expiration)
+	if err == nil {
+		span.AddEvent("attempt succeeded")
+	}
+	end(span, err, nil)
+	return l.wrap(lock, key), err
+}
+
+func (l *LockClient) wrap(lock *gcache.Lock, key string) *Lock {
+	if lock == nil {
+		return nil
+	}
+	return &Lock{
+		Lock:   lock,
+		key:    key,
+		client: l,
+	}
+}
+
+// Lock 为续约和解锁创建 span, 自动续约的每一次续约也会创建 span
+type Lock struct {
+	*gcache.Lock
+	key    string
+	client *LockClient
+}
+
+func (l *Lock) Refresh(ctx context.Context) error {
+	ctx, span := l.client.start(ctx, "Refresh", l.key)
+	err := l.Lock.Refresh(ctx)
+	end(span, err, nil)
+	return err
+}
+
+// AutoRefresh 每次续约都通过 Refresh 创建一个 span
+func (l *Lock) AutoRefresh(interval time.Duration, timeout time.Duration) error {
+	return l.Lock.AutoRefreshWith(interval, timeout, l.Refresh)
+}
+
+func (l *Lock) Unlock(ctx context.Context) error {
+	ctx, span := l.client.start(ctx, "Unlock", l.key)
+	err := l.Lock.Unlock(ctx)
+	end(span, err, nil)
+	return err
+}
+
+// tracingRetry 每次抢锁失败, 决定是否重试的时候记录一个事件
+type tracingRetry struct {
+	gcache.RetryStrategy
+	span trace.Span
+	cnt  int
+}
+
+func (t *tracingRetry) Next() (time.Duration, bool) {
+	interval, ok := t.RetryStrategy.Next()
+	if ok {
+		t.cnt++
+	}
+	t.span.AddEvent("attempt failed", trace.WithAttributes(
+		attribute.Bool("gcache.lock.retry", ok),
+		attribute.Int64("gcache.lock.interval_ms", interval.Milliseconds())))
+	return interval, ok
+}
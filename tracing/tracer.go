@@ -0,0 +1,71 @@
+// Package tracing 使用 OpenTelemetry 为缓存, 加载和分布式锁创建 span
+package tracing
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/NotFound1911/gcache/tracing"
+
+const (
+	attrName    = attribute.Key("gcache.name")
+	attrKey     = attribute.Key("gcache.key")
+	attrHit     = attribute.Key("gcache.hit")
+	attrRetries = attribute.Key("gcache.lock.retries")
+)
+
+type TracerOption func(t *Tracer)
+
+// Tracer 保存创建 span 需要的配置, 多个缓存和锁可以共用一个 Tracer
+type Tracer struct {
+	provider trace.TracerProvider
+	tracer   trace.Tracer
+	hashKey  bool
+}
+
+func NewTracer(opts ...TracerOption) *Tracer {
+	res := &Tracer{
+		provider: otel.GetTracerProvider(),
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	res.tracer = res.provider.Tracer(instrumentationName)
+	return res
+}
+
+// BuildTracerWithProvider 使用指定的 TracerProvider, 默认使用全局的
+func BuildTracerWithProvider(provider trace.TracerProvider) TracerOption {
+	return func(t *Tracer) {
+		t.provider = provider
+	}
+}
+
+// BuildTracerWithHashedKey key 里面可能有敏感信息, 开启之后只记录 key 的哈希值
+func BuildTracerWithHashedKey() TracerOption {
+	return func(t *Tracer) {
+		t.hashKey = true
+	}
+}
+
+func (t *Tracer) keyAttr(key string) attribute.KeyValue {
+	if t.hashKey {
+		sum := sha256.Sum256([]byte(key))
+		return attrKey.String(hex.EncodeToString(sum[:8]))
+	}
+	return attrKey.String(key)
+}
+
+// end 记录错误并结束 span, ignore 返回 true 的错误不会被当成失败
+func end(span trace.Span, err error, ignore func(err error) bool) {
+	if err != nil && (ignore == nil || !ignore(err)) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,155 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/NotFound1911/gcache"
+	"github.com/NotFound1911/gcache/clock/clocktest"
+	"github.com/NotFound1911/gcache/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+	"time"
+)
+
+func newTestTracer(opts ...TracerOption) (*Tracer, *tracetest.SpanRecorder) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	return NewTracer(append(opts, BuildTracerWithProvider(tp))...), sr
+}
+
+func attrs(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	res := make(map[attribute.Key]attribute.Value, len(span.Attributes()))
+	for _, kv := range span.Attributes() {
+		res[kv.Key] = kv.Value
+	}
+	return res
+}
+
+func TestCache(t *testing.T) {
+	tracer, sr := newTestTracer()
+	local := gcache.NewMapCache(time.Minute)
+	defer local.Close()
+	c := &gcache.ReadTroughCache{
+		Cache: tracer.WrapCache("local", local),
+		LoadFunc: tracer.WrapLoadFunc("db", func(ctx context.Context, key string) (any, error) {
+			return nil, errors.New("mock db error")
+		}),
+		Expiration: time.Minute,
+	}
+	ctx := context.Background()
+	require.NoError(t, c.Set(ctx, "key1", "val1", time.Minute))
+	val, err := c.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "val1", val)
+	_, err = c.Get(ctx, "key2")
+	require.Error(t, err)
+
+	spans := sr.Ended()
+	require.GreaterOrEqual(t, len(spans), 3)
+	assert.Equal(t, "gcache.Set", spans[0].Name())
+	assert.Equal(t, "gcache.Get", spans[1].Name())
+	assert.Equal(t, true, attrs(spans[1])[attrHit].AsBool())
+	assert.Equal(t, "key1", attrs(spans[1])[attrKey].AsString())
+	// 未命中不是错误
+	assert.Equal(t, "gcache.Get", spans[2].Name())
+	assert.Equal(t, false, attrs(spans[2])[attrHit].AsBool())
+	assert.Equal(t, codes.Unset, spans[2].Status().Code)
+}
+
+func TestTracer_WrapLoadFunc(t *testing.T) {
+	tracer, sr := newTestTracer(BuildTracerWithHashedKey())
+	fn := tracer.WrapLoadFunc("db", func(ctx context.Context, key string) (any, error) {
+		return nil, errors.New("mock db error")
+	})
+	_, err := fn(context.Background(), "user:1")
+	require.Error(t, err)
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "gcache.Load", spans[0].Name())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "db", attrs(spans[0])[attrName].AsString())
+	assert.NotEqual(t, "user:1", attrs(spans[0])[attrKey].AsString())
+	assert.Len(t, attrs(spans[0])[attrKey].AsString(), 16)
+}
+
+func TestLockClient(t *testing.T) {
+	tracer, sr := newTestTracer()
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := mocks.NewMockCmdable(ctrl)
+	locked := redis.NewCmd(ctx)
+	locked.SetVal("")
+	ok := redis.NewCmd(ctx)
+	ok.SetVal("OK")
+	unlocked := redis.NewCmd(ctx)
+	unlocked.SetVal(int64(1))
+	gomock.InOrder(
+		cmd.EXPECT().Eval(gomock.Any(), gomock.Any(), []string{"key1"}, gomock.Any(), gomock.Any()).
+			Times(2).Return(locked),
+		cmd.EXPECT().Eval(gomock.Any(), gomock.Any(), []string{"key1"}, gomock.Any(), gomock.Any()).Return(ok),
+		cmd.EXPECT().Eval(gomock.Any(), gomock.Any(), []string{"key1"}, gomock.Any()).Return(unlocked),
+	)
+	client := tracer.WrapClient("lock", gcache.NewClient(cmd))
+	lock, err := client.Lock(ctx, "key1", time.Minute, time.Second,
+		&gcache.FixedInterval{Interval: time.Millisecond, MaxCnt: 3})
+	require.NoError(t, err)
+	require.NoError(t, lock.Unlock(ctx))
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "gcache.lock.Lock", spans[0].Name())
+	assert.Equal(t, int64(2), attrs(spans[0])[attrRetries].AsInt64())
+	require.Len(t, spans[0].Events(), 3)
+	assert.Equal(t, "attempt succeeded", spans[0].Events()[2].Name)
+	assert.Equal(t, "gcache.lock.Unlock", spans[1].Name())
+}
+
+func TestLock_AutoRefresh(t *testing.T) {
+	tracer, sr := newTestTracer()
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := mocks.NewMockCmdable(ctrl)
+	cmd.EXPECT().SetNX(gomock.Any(), "key1", gomock.Any(), time.Minute).Return(redis.NewBoolResult(true, nil))
+	refreshed := redis.NewCmd(ctx)
+	refreshed.SetVal(int64(1))
+	cmd.EXPECT().Eval(gomock.Any(), gomock.Any(), []string{"key1"}, gomock.Any(), float64(60)).
+		Times(2).Return(refreshed)
+	unlocked := redis.NewCmd(ctx)
+	unlocked.SetVal(int64(1))
+	cmd.EXPECT().Eval(gomock.Any(), gomock.Any(), []string{"key1"}, gomock.Any()).Return(unlocked)
+
+	clk := clocktest.NewFakeClock(time.Now())
+	client := tracer.WrapClient("lock", gcache.NewClient(cmd, gcache.BuildClientWithClock(clk)))
+	lock, err := client.TryLock(ctx, "key1", time.Minute)
+	require.NoError(t, err)
+	done := make(chan error, 1)
+	go func() {
+		done <- lock.AutoRefresh(time.Second, time.Second)
+	}()
+	for i := 0; i < 2; i++ {
+		clk.BlockUntil(1)
+		clk.Advance(time.Second)
+		require.Eventually(t, func() bool {
+			return len(sr.Ended()) == i+2
+		}, time.Second, time.Millisecond)
+	}
+	require.NoError(t, lock.Unlock(ctx))
+	require.NoError(t, <-done)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 4)
+	assert.Equal(t, "gcache.lock.TryLock", spans[0].Name())
+	assert.Equal(t, "attempt succeeded", spans[0].Events()[0].Name)
+	assert.Equal(t, "gcache.lock.Refresh", spans[1].Name())
+	assert.Equal(t, "gcache.lock.Refresh", spans[2].Name())
+	assert.Equal(t, "gcache.lock.Unlock", spans[3].Name())
+}
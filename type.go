@@ -19,3 +19,14 @@ type Cache interface {
 
 	LoadAndDelete(ctx context.Context, key string) (any, error)
 }
+
+// NoExpiration GetWithTTL 返回的剩余过期时间, 表示数据永不过期
+const NoExpiration time.Duration = -1
+
+// TTLCache 可以同时读取数据和剩余过期时间的缓存, MapCache 和 RedisCache 都实现了这个接口
+// 需要在数据真正过期之前做一些处理的装饰器, 例如 StaleWhileRevalidateCache, 依赖这个接口
+type TTLCache interface {
+	Cache
+	// GetWithTTL 返回数据和它的剩余过期时间, 永不过期的数据返回 NoExpiration
+	GetWithTTL(ctx context.Context, key string) (any, time.Duration, error)
+}
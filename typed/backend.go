@@ -0,0 +1,30 @@
+package typed
+
+import (
+	"github.com/NotFound1911/gcache"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// MapCache 类型安全的 gcache.MapCache
+type MapCache[V any] struct {
+	*Adapter[string, V]
+	cache *gcache.MapCache
+}
+
+func NewMapCache[V any](interval time.Duration, opts ...gcache.MapCacheOption) *MapCache[V] {
+	c := gcache.NewMapCache(interval, opts...)
+	return &MapCache[V]{
+		Adapter: FromCache[string, V](c, nil),
+		cache:   c,
+	}
+}
+
+func (m *MapCache[V]) Close() error {
+	return m.cache.Close()
+}
+
+// NewRedisCache 类型安全的 gcache.RedisCache, 值使用 JSON 序列化之后存储
+func NewRedisCache[V any](client redis.Cmdable) *Adapter[string, V] {
+	return FromCacheWithCodec[string, V](gcache.NewRedisCache(client), nil, JSONCodec[V]{})
+}
@@ -0,0 +1,112 @@
+// Package typed 提供类型安全的缓存接口, 以及和 gcache.Cache 之间的相互转换
+package typed
+
+import (
+	"context"
+	"fmt"
+	"github.com/NotFound1911/gcache"
+	"time"
+)
+
+type Cache[K comparable, V any] interface {
+	Set(ctx context.Context, key K, val V, expiration time.Duration) error
+	Get(ctx context.Context, key K) (V, error)
+	Delete(ctx context.Context, key K) error
+	LoadAndDelete(ctx context.Context, key K) (V, error)
+}
+
+// Adapter 把 gcache.Cache 转换成 Cache[K, V]
+// key 通过 keyFn 转换成字符串, 值通过 Codec 编解码
+type Adapter[K comparable, V any] struct {
+	cache gcache.Cache
+	keyFn func(key K) string
+	codec Codec[V]
+}
+
+var _ Cache[string, any] = (*Adapter[string, any])(nil)
+
+// FromCache keyFn 为 nil 时使用 fmt.Sprint 转换 key
+// 值原样存进 c, 读出来的值类型不对时返回 gcache.ErrTypeMismatch
+func FromCache[K comparable, V any](c gcache.Cache, keyFn func(key K) string) *Adapter[K, V] {
+	return FromCacheWithCodec[K, V](c, keyFn, AssertCodec[V]{})
+}
+
+// FromCacheWithCodec 和 FromCache 一样, 但是使用 codec 编解码值
+func FromCacheWithCodec[K comparable, V any](c gcache.Cache, keyFn func(key K) string, codec Codec[V]) *Adapter[K, V] {
+	if keyFn == nil {
+		keyFn = func(key K) string {
+			return fmt.Sprint(key)
+		}
+	}
+	return &Adapter[K, V]{
+		cache: c,
+		keyFn: keyFn,
+		codec: codec,
+	}
+}
+
+func (a *Adapter[K, V]) Set(ctx context.Context, key K, val V, expiration time.Duration) error {
+	k := a.keyFn(key)
+	data, err := a.codec.Encode(k, val)
+	if err != nil {
+		return err
+	}
+	return a.cache.Set(ctx, k, data, expiration)
+}
+
+func (a *Adapter[K, V]) Get(ctx context.Context, key K) (V, error) {
+	k := a.keyFn(key)
+	val, err := a.cache.Get(ctx, k)
+	if err != nil {
+		var v V
+		return v, err
+	}
+	return a.codec.Decode(k, val)
+}
+
+func (a *Adapter[K, V]) Delete(ctx context.Context, key K) error {
+	return a.cache.Delete(ctx, a.keyFn(key))
+}
+
+func (a *Adapter[K, V]) LoadAndDelete(ctx context.Context, key K) (V, error) {
+	k := a.keyFn(key)
+	val, err := a.cache.LoadAndDelete(ctx, k)
+	if err != nil {
+		var v V
+		return v, err
+	}
+	return a.codec.Decode(k, val)
+}
+
+// untyped 把 Cache[string, V] 转换成 gcache.Cache
+type untyped[V any] struct {
+	cache Cache[string, V]
+}
+
+var _ gcache.Cache = untyped[any]{}
+
+// ToCache 写入的值类型不对时返回 gcache.ErrTypeMismatch
+// 即使 c 是从 gcache.Cache 转换过来的也会包装一层, 保留 key 的转换和类型检查
+func ToCache[V any](c Cache[string, V]) gcache.Cache {
+	return untyped[V]{cache: c}
+}
+
+func (u untyped[V]) Set(ctx context.Context, key string, val any, expiration time.Duration) error {
+	v, ok := val.(V)
+	if !ok {
+		return fmt.Errorf("%w, key: %s, 期望类型: %T, 实际类型: %T", gcache.ErrTypeMismatch, key, v, val)
+	}
+	return u.cache.Set(ctx, key, v, expiration)
+}
+
+func (u untyped[V]) Get(ctx context.Context, key string) (any, error) {
+	return u.cache.Get(ctx, key)
+}
+
+func (u untyped[V]) Delete(ctx context.Context, key string) error {
+	return u.cache.Delete(ctx, key)
+}
+
+func (u untyped[V]) LoadAndDelete(ctx context.Context, key string) (any, error) {
+	return u.cache.LoadAndDelete(ctx, key)
+}
@@ -0,0 +1,107 @@
+package typed
+
+import (
+	"context"
+	"errors"
+	"github.com/NotFound1911/gcache"
+	"github.com/NotFound1911/gcache/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type user struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestMapCache(t *testing.T) {
+	c := NewMapCache[user](time.Minute)
+	defer c.Close()
+	ctx := context.Background()
+	require.NoError(t, c.Set(ctx, "key1", user{Name: "Tom", Age: 18}, time.Minute))
+	val, err := c.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, user{Name: "Tom", Age: 18}, val)
+
+	_, err = c.Get(ctx, "key2")
+	assert.Error(t, err)
+
+	// 通过无类型的接口写入错误的类型
+	require.NoError(t, c.cache.Set(ctx, "key3", "not a user", time.Minute))
+	_, err = c.Get(ctx, "key3")
+	assert.True(t, errors.Is(err, gcache.ErrTypeMismatch))
+
+	val, err = c.LoadAndDelete(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "Tom", val.Name)
+}
+
+func TestFromCache(t *testing.T) {
+	local := gcache.NewMapCache(time.Minute)
+	defer local.Close()
+	c := FromCache[int, int64](local, func(key int) string {
+		return "user:" + strconv.Itoa(key)
+	})
+	ctx := context.Background()
+	require.NoError(t, c.Set(ctx, 1, 100, time.Minute))
+	val, err := local.Get(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), val)
+	v, err := c.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), v)
+	require.NoError(t, c.Delete(ctx, 1))
+	_, err = local.Get(ctx, "user:1")
+	assert.Error(t, err)
+}
+
+func TestToCache(t *testing.T) {
+	local := gcache.NewMapCache(time.Minute)
+	defer local.Close()
+	ctx := context.Background()
+	// 从 gcache.Cache 转换过来的, 转换回去依旧使用 keyFn 并且检查类型
+	raw := ToCache[int](FromCache[string, int](local, func(key string) string {
+		return "user:" + key
+	}))
+	err := raw.Set(ctx, "1", "notint", time.Minute)
+	assert.True(t, errors.Is(err, gcache.ErrTypeMismatch))
+	require.NoError(t, raw.Set(ctx, "1", 100, time.Minute))
+	val, err := local.Get(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, 100, val)
+	_, err = local.Get(ctx, "1")
+	assert.True(t, gcache.IsKeyNotFound(err))
+
+	c := ToCache[user](NewRedisCache[user](nil))
+	err = c.Set(context.Background(), "key1", "not a user", time.Minute)
+	assert.True(t, errors.Is(err, gcache.ErrTypeMismatch))
+}
+
+func TestRedisCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx := context.Background()
+	cmd := mocks.NewMockCmdable(ctrl)
+	status := redis.NewStatusCmd(ctx)
+	status.SetVal("OK")
+	cmd.EXPECT().Set(ctx, "key1", `{"name":"Tom","age":18}`, time.Minute).Return(status)
+	str := redis.NewStringCmd(ctx)
+	str.SetVal(`{"name":"Jerry","age":20}`)
+	cmd.EXPECT().Get(ctx, "key2").Return(str)
+	bad := redis.NewStringCmd(ctx)
+	bad.SetVal("not json")
+	cmd.EXPECT().Get(ctx, "key3").Return(bad)
+
+	c := NewRedisCache[user](cmd)
+	require.NoError(t, c.Set(ctx, "key1", user{Name: "Tom", Age: 18}, time.Minute))
+	val, err := c.Get(ctx, "key2")
+	require.NoError(t, err)
+	assert.Equal(t, user{Name: "Jerry", Age: 20}, val)
+	_, err = c.Get(ctx, "key3")
+	assert.True(t, errors.Is(err, gcache.ErrTypeMismatch))
+}
@@ -0,0 +1,56 @@
+package typed
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/NotFound1911/gcache"
+)
+
+// Codec 负责值和底层缓存中存储的数据之间的转换
+type Codec[V any] interface {
+	Encode(key string, val V) (any, error)
+	Decode(key string, data any) (V, error)
+}
+
+// AssertCodec 原样存储, 读取时做类型断言, 适用于 MapCache 这种直接保存对象的缓存
+type AssertCodec[V any] struct{}
+
+func (AssertCodec[V]) Encode(key string, val V) (any, error) {
+	return val, nil
+}
+
+func (AssertCodec[V]) Decode(key string, data any) (V, error) {
+	res, ok := data.(V)
+	if !ok {
+		return res, fmt.Errorf("%w, key: %s, 期望类型: %T, 实际类型: %T", gcache.ErrTypeMismatch, key, res, data)
+	}
+	return res, nil
+}
+
+// JSONCodec 使用 JSON 序列化, 适用于 RedisCache 这种只能保存字符串的缓存
+type JSONCodec[V any] struct{}
+
+func (JSONCodec[V]) Encode(key string, val V) (any, error) {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (JSONCodec[V]) Decode(key string, data any) (V, error) {
+	var res V
+	var raw []byte
+	switch d := data.(type) {
+	case string:
+		raw = []byte(d)
+	case []byte:
+		raw = d
+	default:
+		return res, fmt.Errorf("%w, key: %s, 期望类型: string, 实际类型: %T", gcache.ErrTypeMismatch, key, data)
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return res, fmt.Errorf("%w, key: %s, 原因: %s", gcache.ErrTypeMismatch, key, err.Error())
+	}
+	return res, nil
+}
@@ -0,0 +1,21 @@
+package gcache
+
+import (
+	"context"
+	"time"
+)
+
+// WriteAroundCache 写绕过
+// Set 只写入数据源并删除缓存中的旧值, 适合很大并且很少被读取的数据, 下次读取时再加载
+type WriteAroundCache struct {
+	Cache
+	StoreFunc func(ctx context.Context, key string, val any) error
+}
+
+func (w *WriteAroundCache) Set(ctx context.Context, key string, val any, expiration time.Duration) error {
+	err := w.StoreFunc(ctx, key, val)
+	if err != nil {
+		return err
+	}
+	return w.Cache.Delete(ctx, key)
+}
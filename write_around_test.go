@@ -0,0 +1,60 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestWriteAroundCache_Set(t *testing.T) {
+	testCases := []struct {
+		name     string
+		storeErr error
+
+		wantErr    error
+		wantStored any
+		wantCached any
+	}{
+		{
+			name:       "store and invalidate",
+			wantStored: "new",
+		},
+		{
+			name:       "store error",
+			storeErr:   errors.New("db error"),
+			wantErr:    errors.New("db error"),
+			wantCached: "old",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			local := NewMapCache(time.Minute)
+			defer local.Close()
+			require.NoError(t, local.Set(context.Background(), "key1", "old", time.Minute))
+			var stored any
+			c := &WriteAroundCache{
+				Cache: local,
+				StoreFunc: func(ctx context.Context, key string, val any) error {
+					if tc.storeErr != nil {
+						return tc.storeErr
+					}
+					stored = val
+					return nil
+				},
+			}
+			err := c.Set(context.Background(), "key1", "new", time.Minute)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantStored, stored)
+			val, err := local.Get(context.Background(), "key1")
+			if tc.wantCached == nil {
+				assert.Equal(t, fmt.Errorf("%w, key: %s", ErrKeyNotFound, "key1"), err)
+				return
+			}
+			assert.Equal(t, tc.wantCached, val)
+		})
+	}
+}
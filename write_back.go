@@ -0,0 +1,259 @@
+package gcache
+
+import (
+	"context"
+	"fmt"
+	"github.com/NotFound1911/gcache/clock"
+	"sync"
+	"time"
+)
+
+// BatchStoreFunc 一次写入多个 key
+type BatchStoreFunc func(ctx context.Context, entries []Entry) error
+
+type WriteBackOption func(w *WriteBackCache)
+
+// WriteBackCache 写回
+// Set 只写入本地缓存并记录为脏数据, 脏数据按照时间间隔或者数量阈值批量写入 BatchStoreFunc.
+// 脏数据在写入之前过期或者被淘汰时, 会带着被淘汰的值一起写入, 关闭时写入所有剩余的脏数据.
+// 被 Delete 的 key 不会再写入
+type WriteBackCache struct {
+	*MapCache
+	local     LocalCache
+	storeFunc BatchStoreFunc
+	interval  time.Duration
+	threshold int
+	timeout   time.Duration
+	retry     func() RetryStrategy
+	onError   func(entries []Entry, err error)
+	clock     clock.Clock
+
+	dirtyMu sync.Mutex
+	dirty   map[string]struct{}
+	evicted []Entry // 还没有写入就被淘汰的脏数据
+
+	flushMu sync.Mutex    // 保证同一个 key 的写入顺序
+	flush   chan struct{} // 达到阈值或者有脏数据被淘汰时通知后台协程写入
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewWriteBackCache c 可以是 MaxCntCache 或者 MaxSizeCache, 写入时依旧受容量限制, 因为容量被淘汰的脏数据会被写回
+// 创建之后应该只通过 WriteBackCache 写入, 直接写入 c 的数据不会被记录为脏数据
+func NewWriteBackCache(c LocalCache, storeFunc BatchStoreFunc, opts ...WriteBackOption) *WriteBackCache {
+	res := &WriteBackCache{
+		MapCache:  c.mapCache(),
+		local:     c,
+		storeFunc: storeFunc,
+		interval:  time.Second,
+		threshold: 100,
+		timeout:   time.Second * 3,
+		retry: func() RetryStrategy {
+			return &FixedInterval{Interval: time.Millisecond * 100, MaxCnt: 3}
+		},
+		onError: func(entries []Entry, err error) {},
+		clock:   c.mapCache().clock,
+		dirty:   make(map[string]struct{}, 128),
+		flush:   make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	origin := res.MapCache.onEvicted
+	// onEvicted 总是在持有 MapCache 的锁的时候调用
+	res.onEvicted = func(key string, val any, reason EvictionReason) {
+		switch reason {
+		case EvictionExpired, EvictionCapacity, EvictionClosed:
+			res.evict(key, val)
+		case EvictionDeleted:
+			res.dirtyMu.Lock()
+			delete(res.dirty, key)
+			res.dirtyMu.Unlock()
+		}
+		if origin != nil {
+			origin(key, val, reason)
+		}
+	}
+	ticker := res.clock.NewTicker(res.interval)
+	go res.loop(ticker)
+	return res
+}
+
+// BuildWriteBackWithInterval 定时写入的间隔, 默认 1 秒
+func BuildWriteBackWithInterval(interval time.Duration) WriteBackOption {
+	return func(w *WriteBackCache) {
+		w.interval = interval
+	}
+}
+
+// BuildWriteBackWithThreshold 脏数据达到 threshold 个时不等定时直接写入, 默认 100
+func BuildWriteBackWithThreshold(threshold int) WriteBackOption {
+	return func(w *WriteBackCache) {
+		w.threshold = threshold
+	}
+}
+
+// BuildWriteBackWithTimeout 每次调用 BatchStoreFunc 的超时时间, 默认 3 秒
+func BuildWriteBackWithTimeout(timeout time.Duration) WriteBackOption {
+	return func(w *WriteBackCache) {
+		w.timeout = timeout
+	}
+}
+
+// BuildWriteBackWithRetry 每次写入失败时调用 newRetry 创建新的重试策略, 默认间隔 100 毫秒重试 3 次
+func BuildWriteBackWithRetry(newRetry func() RetryStrategy) WriteBackOption {
+	return func(w *WriteBackCache) {
+		w.retry = newRetry
+	}
+}
+
+// BuildWriteBackWithErrorHandler 重试之后依旧写入失败的数据会交给 fn, 之后不会再写入
+func BuildWriteBackWithErrorHandler(fn func(entries []Entry, err error)) WriteBackOption {
+	return func(w *WriteBackCache) {
+		w.onError = fn
+	}
+}
+
+func (w *WriteBackCache) Set(ctx context.Context, key string, val any, expiration time.Duration) error {
+	if w.closing.Load() {
+		return ErrCacheClosed
+	}
+	// 在 MapCache 的锁里面标记, 否则刚写入就被淘汰的 key 会丢失
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.add(key, val, expirationOf(w.strategy, key, expiration))
+}
+
+func (w *WriteBackCache) SetMulti(ctx context.Context, entries []Entry, expiration time.Duration) ([]Result, error) {
+	if w.closing.Load() {
+		return nil, ErrCacheClosed
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	res := make([]Result, len(entries))
+	for i, e := range entries {
+		res[i] = Result{Key: e.Key, Err: w.add(e.Key, e.Val, expirationOf(w.strategy, e.Key, expiration))}
+	}
+	return res, nil
+}
+
+// add 调用方需要持有 MapCache 的写锁
+func (w *WriteBackCache) add(key string, val any, expiration time.Duration) error {
+	if err := w.local.add(key, val, expiration); err != nil {
+		return err
+	}
+	w.dirtyMu.Lock()
+	defer w.dirtyMu.Unlock()
+	w.dirty[key] = struct{}{}
+	if len(w.dirty)+len(w.evicted) >= w.threshold {
+		w.notify()
+	}
+	return nil
+}
+
+// evict 调用方需要持有 MapCache 的锁
+func (w *WriteBackCache) evict(key string, val any) {
+	w.dirtyMu.Lock()
+	defer w.dirtyMu.Unlock()
+	if _, ok := w.dirty[key]; !ok {
+		return
+	}
+	delete(w.dirty, key)
+	w.evicted = append(w.evicted, Entry{Key: key, Val: val})
+	w.notify()
+}
+
+func (w *WriteBackCache) notify() {
+	select {
+	case w.flush <- struct{}{}:
+	default:
+	}
+}
+
+func (w *WriteBackCache) loop(ticker clock.Ticker) {
+	defer close(w.done)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+		case <-w.flush:
+		case <-w.stop:
+			return
+		}
+		// 错误已经交给 onError
+		_ = w.Flush(context.Background())
+	}
+}
+
+// Flush 立刻写入所有的脏数据, 失败的数据在重试之后交给 onError
+func (w *WriteBackCache) Flush(ctx context.Context) error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+	entries := w.takeDirty()
+	if len(entries) == 0 {
+		return nil
+	}
+	err := w.store(ctx, entries)
+	if err != nil {
+		w.onError(entries, err)
+	}
+	return err
+}
+
+// takeDirty 取出所有的脏数据, 先淘汰的在前面
+func (w *WriteBackCache) takeDirty() []Entry {
+	// 持有 MapCache 的锁, 避免取出之后, 读取值之前 key 被淘汰
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	w.dirtyMu.Lock()
+	defer w.dirtyMu.Unlock()
+	res := w.evicted
+	w.evicted = nil
+	for key := range w.dirty {
+		if itm, ok := w.data[key]; ok {
+			res = append(res, Entry{Key: key, Val: itm.val})
+		}
+	}
+	w.dirty = make(map[string]struct{}, 128)
+	return res
+}
+
+func (w *WriteBackCache) store(ctx context.Context, entries []Entry) error {
+	retry := w.retry()
+	var timer clock.Timer
+	for {
+		sctx, cancel := context.WithTimeout(ctx, w.timeout)
+		err := w.storeFunc(sctx, entries)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		interval, ok := retry.Next()
+		if !ok {
+			return fmt.Errorf("%w, 超出重试限制, 原因: %w", ErrFailedToWriteBack, err)
+		}
+		if timer == nil {
+			timer = w.clock.NewTimer(interval)
+			defer timer.Stop()
+		} else {
+			timer.Reset(interval)
+		}
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close 写入所有剩余的脏数据之后关闭缓存
+func (w *WriteBackCache) Close() error {
+	if err := w.MapCache.Close(); err != nil {
+		return err
+	}
+	close(w.stop)
+	<-w.done
+	return w.Flush(context.Background())
+}
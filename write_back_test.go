@@ -0,0 +1,195 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"github.com/NotFound1911/gcache/clock/clocktest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchStore 记录每次写回的数据
+type batchStore struct {
+	mu      sync.Mutex
+	batches [][]Entry
+	errs    []error // 按顺序返回, 用完之后都成功
+}
+
+func (b *batchStore) store(ctx context.Context, entries []Entry) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.errs) > 0 {
+		err := b.errs[0]
+		b.errs = b.errs[1:]
+		return err
+	}
+	b.batches = append(b.batches, entries)
+	return nil
+}
+
+func (b *batchStore) stored() [][]Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.batches
+}
+
+func TestWriteBackCache_Flush(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts []WriteBackOption
+		// act 写入数据并触发写回
+		act func(t *testing.T, clk *clocktest.FakeClock, c *WriteBackCache)
+
+		wantBatches [][]Entry
+	}{
+		{
+			name: "interval",
+			act: func(t *testing.T, clk *clocktest.FakeClock, c *WriteBackCache) {
+				require.NoError(t, c.Set(context.Background(), "key1", 1, time.Minute))
+				require.NoError(t, c.Set(context.Background(), "key1", 2, time.Minute))
+				clk.Advance(time.Second)
+			},
+			wantBatches: [][]Entry{{{Key: "key1", Val: 2}}},
+		},
+		{
+			name: "threshold",
+			opts: []WriteBackOption{BuildWriteBackWithThreshold(2)},
+			act: func(t *testing.T, clk *clocktest.FakeClock, c *WriteBackCache) {
+				_, err := c.SetMulti(context.Background(), []Entry{
+					{Key: "key1", Val: 1},
+					{Key: "key2", Val: 2},
+				}, time.Minute)
+				require.NoError(t, err)
+			},
+			wantBatches: [][]Entry{{{Key: "key1", Val: 1}, {Key: "key2", Val: 2}}},
+		},
+		{
+			name: "evicted",
+			act: func(t *testing.T, clk *clocktest.FakeClock, c *WriteBackCache) {
+				require.NoError(t, c.Set(context.Background(), "key1", 1, time.Millisecond*100))
+				// 定时写回之前过期, 写回过期时的值
+				clk.Advance(time.Millisecond * 500)
+			},
+			wantBatches: [][]Entry{{{Key: "key1", Val: 1}}},
+		},
+		{
+			name: "deleted",
+			act: func(t *testing.T, clk *clocktest.FakeClock, c *WriteBackCache) {
+				require.NoError(t, c.Set(context.Background(), "key1", 1, time.Minute))
+				require.NoError(t, c.Set(context.Background(), "key2", 2, time.Minute))
+				require.NoError(t, c.Delete(context.Background(), "key1"))
+				clk.Advance(time.Second)
+			},
+			wantBatches: [][]Entry{{{Key: "key2", Val: 2}}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clk := clocktest.NewFakeClock(time.Now())
+			store := &batchStore{}
+			c := NewWriteBackCache(NewMapCache(time.Millisecond*200, BuildMapCacheWithClock(clk)), store.store, tc.opts...)
+			defer c.Close()
+			tc.act(t, clk, c)
+			require.Eventually(t, func() bool {
+				return len(store.stored()) == len(tc.wantBatches)
+			}, time.Second, time.Millisecond)
+			for i, batch := range store.stored() {
+				assert.ElementsMatch(t, tc.wantBatches[i], batch)
+			}
+		})
+	}
+}
+
+func TestWriteBackCache_Retry(t *testing.T) {
+	clk := clocktest.NewFakeClock(time.Now())
+	dbErr := errors.New("db error")
+	store := &batchStore{errs: []error{dbErr, dbErr}}
+	c := NewWriteBackCache(NewMapCache(time.Minute, BuildMapCacheWithClock(clk)), store.store,
+		BuildWriteBackWithInterval(time.Hour),
+		BuildWriteBackWithRetry(func() RetryStrategy {
+			return &FixedInterval{Interval: time.Second, MaxCnt: 2}
+		}))
+	defer c.Close()
+	require.NoError(t, c.Set(context.Background(), "key1", 1, time.Minute))
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.Flush(context.Background())
+	}()
+	// 两个 ticker 加上重试的 timer
+	for i := 0; i < 2; i++ {
+		clk.BlockUntil(3)
+		clk.Advance(time.Second)
+	}
+	require.NoError(t, <-errCh)
+	assert.Equal(t, [][]Entry{{{Key: "key1", Val: 1}}}, store.stored())
+}
+
+func TestWriteBackCache_ErrorHandler(t *testing.T) {
+	dbErr := errors.New("db error")
+	store := &batchStore{errs: []error{dbErr}}
+	var failed []Entry
+	var failedErr error
+	c := NewWriteBackCache(NewMapCache(time.Minute), store.store,
+		BuildWriteBackWithInterval(time.Hour),
+		BuildWriteBackWithRetry(func() RetryStrategy {
+			return &FixedInterval{}
+		}),
+		BuildWriteBackWithErrorHandler(func(entries []Entry, err error) {
+			failed = entries
+			failedErr = err
+		}))
+	defer c.Close()
+	require.NoError(t, c.Set(context.Background(), "key1", 1, time.Minute))
+	err := c.Flush(context.Background())
+	assert.ErrorIs(t, err, ErrFailedToWriteBack)
+	assert.ErrorIs(t, err, dbErr)
+	assert.Equal(t, err, failedErr)
+	assert.Equal(t, []Entry{{Key: "key1", Val: 1}}, failed)
+	// 失败的数据不会再写入
+	require.NoError(t, c.Flush(context.Background()))
+	assert.Empty(t, store.stored())
+}
+
+func TestWriteBackCache_Close(t *testing.T) {
+	store := &batchStore{}
+	c := NewWriteBackCache(NewMapCache(time.Minute), store.store, BuildWriteBackWithInterval(time.Hour))
+	require.NoError(t, c.Set(context.Background(), "key1", 1, time.Minute))
+	require.NoError(t, c.Set(context.Background(), "key2", 2, 0))
+	require.NoError(t, c.Close())
+	require.Len(t, store.stored(), 1)
+	assert.ElementsMatch(t, []Entry{{Key: "key1", Val: 1}, {Key: "key2", Val: 2}}, store.stored()[0])
+	assert.Equal(t, ErrCacheClosed, c.Set(context.Background(), "key3", 3, time.Minute))
+	assert.ErrorIs(t, c.Close(), ErrCacheClosed)
+}
+
+func TestWriteBackCache_Capacity(t *testing.T) {
+	store := &batchStore{}
+	limited := NewMaxCntCache(NewMapCache(time.Minute, BuildMapCacheWithLRU()), 1)
+	c := NewWriteBackCache(limited, store.store, BuildWriteBackWithInterval(time.Hour))
+	defer c.Close()
+	ctx := context.Background()
+	require.NoError(t, c.Set(ctx, "key1", 1, time.Minute))
+	require.NoError(t, c.Set(ctx, "key2", 2, time.Minute))
+	// key1 因为容量被淘汰, 带着淘汰时的值写回
+	require.Eventually(t, func() bool {
+		return len(store.stored()) > 0
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, Entry{Key: "key1", Val: 1}, store.stored()[0][0])
+
+	c.mu.RLock()
+	assert.Equal(t, int32(1), limited.cnt)
+	assert.Len(t, c.data, 1)
+	c.mu.RUnlock()
+	_, err := c.Get(ctx, "key1")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	require.NoError(t, c.Flush(ctx))
+	var all []Entry
+	for _, batch := range store.stored() {
+		all = append(all, batch...)
+	}
+	assert.Equal(t, []Entry{{Key: "key1", Val: 1}, {Key: "key2", Val: 2}}, all)
+}
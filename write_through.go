@@ -8,6 +8,8 @@ import (
 type WriteThroughCache struct {
 	Cache
 	StoreFunc func(ctx context.Context, ket string, val any) error
+	// ExpirationStrategy 为空时直接使用 Set 传入的过期时间
+	ExpirationStrategy ExpirationStrategy
 }
 
 func (w *WriteThroughCache) Set(ctx context.Context, key string, val any, expiration time.Duration) error {
@@ -15,18 +17,24 @@ func (w *WriteThroughCache) Set(ctx context.Context, key string, val any, expira
 	if err != nil {
 		return err
 	}
-	return w.Cache.Set(ctx, key, val, expiration)
+	return setWithStrategy(ctx, w.Cache, w.ExpirationStrategy, key, val, expiration)
 }
 
 type WriteThroughCacheV1[T any] struct {
 	Cache
 	StoreFunc func(ctx context.Context, key string, val T) error
+	// ExpirationStrategy 为空时直接使用 Set 传入的过期时间
+	ExpirationStrategy ExpirationStrategy
 }
 
 func (w *WriteThroughCacheV1[T]) Set(ctx context.Context, key string, val any, expiration time.Duration) error {
-	err := w.StoreFunc(ctx, key, val.(T))
+	t, err := assertType[T](key, val)
 	if err != nil {
 		return err
 	}
-	return w.Cache.Set(ctx, key, val, expiration)
+	err = w.StoreFunc(ctx, key, t)
+	if err != nil {
+		return err
+	}
+	return setWithStrategy(ctx, w.Cache, w.ExpirationStrategy, key, val, expiration)
 }
@@ -0,0 +1,99 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/NotFound1911/gcache/clock"
+	"math"
+	"math/rand"
+	"time"
+)
+
+type XFetchOption func(x *XFetchCache)
+
+// XFetchCache 概率提前过期 (XFetch)
+// 缓存中记录加载耗时 delta, 每次命中时根据剩余过期时间 ttl 按照
+// -delta * beta * ln(rand) >= ttl 决定是否提前重新加载.
+// 离过期越近, 加载越慢, 提前加载的概率越大, 多个实例之间也只有少数调用方会提前加载
+type XFetchCache struct {
+	*ReadTroughCache
+	cache *envelopeCache
+	beta  float64
+	clock clock.Clock
+	rand  func() float64 // 返回 (0, 1] 之间的随机数
+}
+
+// NewXFetchCache 加载耗时作为 envelope 的元数据和值一起写入缓存, 剩余过期时间通过 GetWithTTL 读取
+func NewXFetchCache(c TTLCache, loadFunc func(ctx context.Context, key string) (any, error),
+	expiration time.Duration, opts ...XFetchOption) *XFetchCache {
+	res := &XFetchCache{
+		cache: &envelopeCache{TTLCache: c},
+		beta:  1,
+		clock: clock.New(),
+		rand: func() float64 {
+			return 1 - rand.Float64()
+		},
+	}
+	res.ReadTroughCache = &ReadTroughCache{
+		Cache: res.cache,
+		// 记录加载耗时, 由 envelopeCache.Set 一起写入缓存
+		LoadFunc: func(ctx context.Context, key string) (any, error) {
+			start := res.clock.Now()
+			val, err := loadFunc(ctx, key)
+			if err != nil {
+				return nil, err
+			}
+			return &envelope{val: val, meta: uint64(res.clock.Now().Sub(start))}, nil
+		},
+		Expiration: expiration,
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// BuildXFetchWithBeta beta 越大越倾向于提前加载, 默认 1
+func BuildXFetchWithBeta(beta float64) XFetchOption {
+	return func(x *XFetchCache) {
+		x.beta = beta
+	}
+}
+
+// BuildXFetchWithClock 用来测量加载耗时
+func BuildXFetchWithClock(c clock.Clock) XFetchOption {
+	return func(x *XFetchCache) {
+		x.clock = c
+	}
+}
+
+func (x *XFetchCache) Get(ctx context.Context, key string) (any, error) {
+	e, ttl, err := x.cache.getEnvelope(ctx, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		val, err := x.sharedLoad(ctx, key)
+		return unwrapEnvelope(val), err
+	}
+	if err != nil {
+		return nil, err
+	}
+	if x.shouldRecompute(time.Duration(e.meta), ttl) {
+		val, err := x.sharedLoad(ctx, key)
+		// 提前加载失败的时候原来的值还没有过期, 继续使用
+		if err == nil {
+			return unwrapEnvelope(val), nil
+		}
+	}
+	if isTombstone(e.val) {
+		return nil, fmt.Errorf("%w, key: %s", ErrKeyNotFound, key)
+	}
+	return e.val, nil
+}
+
+func (x *XFetchCache) shouldRecompute(delta, ttl time.Duration) bool {
+	if delta <= 0 || ttl == NoExpiration {
+		return false
+	}
+	early := time.Duration(-float64(delta) * x.beta * math.Log(x.rand()))
+	return early >= ttl
+}
@@ -0,0 +1,146 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/NotFound1911/gcache/clock/clocktest"
+	"github.com/NotFound1911/gcache/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestXFetchCache_Get(t *testing.T) {
+	clk := clocktest.NewFakeClock(time.Now())
+	local := NewMapCache(time.Hour, BuildMapCacheWithClock(clk))
+	defer local.Close()
+	load := 0
+	var loadErr error
+	c := NewXFetchCache(local, func(ctx context.Context, key string) (any, error) {
+		load++
+		// 每次加载耗时 1 秒
+		clk.Advance(time.Second)
+		if loadErr != nil {
+			return nil, loadErr
+		}
+		return fmt.Sprintf("v%d", load), nil
+	}, 10*time.Second, BuildXFetchWithClock(clk))
+	// ln(rand) = -3, 提前 3 * delta = 3 秒加载
+	c.rand = func() float64 {
+		return math.Exp(-3)
+	}
+	ctx := context.Background()
+
+	val, err := c.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", val)
+	// 未命中之后直接加载, 不会再读一次缓存
+	assert.Equal(t, int64(1), c.Stats().Misses)
+	itm := local.data["key1"].val.(*envelope)
+	assert.Equal(t, uint64(time.Second), itm.meta)
+
+	clk.Advance(6 * time.Second)
+	val, err = c.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", val)
+	assert.Equal(t, 1, load)
+
+	// 距离过期不到 3 秒, 提前加载
+	clk.Advance(2 * time.Second)
+	val, err = c.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", val)
+	assert.Equal(t, 2, load)
+
+	// 提前加载失败继续使用没有过期的值
+	loadErr = errors.New("db error")
+	clk.Advance(7 * time.Second)
+	val, err = c.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", val)
+	assert.Equal(t, 3, load)
+	assert.Equal(t, int64(1), c.Stats().LoadFailures)
+
+	// rand 为 1 时不会提前加载
+	c.rand = func() float64 {
+		return 1
+	}
+	loadErr = nil
+	val, err = c.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", val)
+	assert.Equal(t, 3, load)
+}
+
+func TestXFetchCache_Redis(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	clk := clocktest.NewFakeClock(time.Now())
+	data, err := (&envelope{val: "old", meta: uint64(time.Second)}).MarshalBinary()
+	require.NoError(t, err)
+	cmd := mocks.NewMockCmdable(ctrl)
+	// 剩余 1 秒过期
+	hit := redis.NewCmd(context.Background())
+	hit.SetVal([]any{string(data), int64(1000)})
+	cmd.EXPECT().Eval(gomock.Any(), luaGetWithTTL, []string{"key1"}).Return(hit)
+	var stored *envelope
+	cmd.EXPECT().Set(gomock.Any(), "key1", gomock.Any(), time.Minute).
+		DoAndReturn(func(ctx context.Context, key string, val any, expiration time.Duration) *redis.StatusCmd {
+			stored = val.(*envelope)
+			status := redis.NewStatusCmd(context.Background())
+			status.SetVal("OK")
+			return status
+		})
+	c := NewXFetchCache(NewRedisCache(cmd), func(ctx context.Context, key string) (any, error) {
+		clk.Advance(time.Millisecond * 500)
+		return "new", nil
+	}, time.Minute, BuildXFetchWithClock(clk), BuildXFetchWithBeta(2))
+	c.rand = func() float64 {
+		return 0.5
+	}
+	// 2 * 1s * ln(2) 大于剩余的 1 秒
+	val, err := c.Get(context.Background(), "key1")
+	require.NoError(t, err)
+	assert.Equal(t, "new", val)
+	require.NotNil(t, stored)
+	assert.Equal(t, "new", stored.val)
+	assert.Equal(t, uint64(time.Millisecond*500), stored.meta)
+	assert.Equal(t, int64(1), c.Stats().LoadSuccesses)
+}
+
+func TestXFetchCache_MaxSize(t *testing.T) {
+	local := NewMaxSizeCache(NewMapCache(time.Minute), 100, nil)
+	defer local.Close()
+	load := 0
+	c := NewXFetchCache(local, func(ctx context.Context, key string) (any, error) {
+		load++
+		return "hello", nil
+	}, time.Minute)
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		val, err := c.Get(ctx, "key1")
+		require.NoError(t, err)
+		assert.Equal(t, "hello", val)
+	}
+	assert.Equal(t, 1, load)
+	// 按照 envelope 里面的值计算大小
+	assert.Equal(t, int64(5), local.Size())
+
+	var sized []any
+	sizer := NewMaxSizeCache(NewMapCache(time.Minute), 100, func(key string, val any) int64 {
+		sized = append(sized, val)
+		return 1
+	})
+	defer sizer.Close()
+	c = NewXFetchCache(sizer, func(ctx context.Context, key string) (any, error) {
+		return 123, nil
+	}, time.Minute)
+	_, err := c.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, []any{123}, sized)
+}